@@ -96,6 +96,9 @@ func main() {
 
 	config.CreateConfiguration()
 
+	// watch maps having watch mode enabled
+	model.StartWatchers()
+
 	addr := ":" + strconv.Itoa(port)
 
 	if noBrowser == false {
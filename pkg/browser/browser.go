@@ -6,10 +6,18 @@
 package browser
 
 import (
+	"errors"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/config"
 )
 
 // ItemType defines type of Item.
@@ -22,12 +30,40 @@ const (
 	ItemDir
 )
 
+// Sort keys
+const (
+	SortName     = "name"
+	SortSize     = "size"
+	SortModified = "modified"
+	// SortType sorts directories first
+	SortType = "type"
+
+	// dirMIMEType is MIME type of directories.
+	dirMIMEType = "inode/directory"
+	// sniffLength is number of bytes read to detect MIME type.
+	sniffLength = 512
+)
+
+// ErrUnknownSort is returned for unknown sort key.
+var ErrUnknownSort = errors.New("unknown sort key")
+
 // Item is file or directory struct.
 type Item struct {
-	Name string   `json:"name"`
-	Path string   `json:"path"`
-	Size int      `json:"size"`
-	Type ItemType `json:"type"`
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Type     ItemType  `json:"type"`
+	Modified time.Time `json:"modified"`
+	// Mode is permission and type bits like "-rw-r--r--",
+	// Perm contains only the permission bits
+	Mode   string `json:"mode"`
+	Perm   uint32 `json:"perm"`
+	Hidden bool   `json:"hidden"`
+	// Target is content of symlink
+	Target string `json:"target"`
+	MIME   string `json:"mime"`
+	// Children is number of entries in directory
+	Children int `json:"children"`
 }
 
 // Sorting technique using programmable sort criteria
@@ -65,18 +101,45 @@ func (s *itemSorter) Less(i, j int) bool {
 	return s.by(&s.items[i], &s.items[j])
 }
 
+// Options for ScanDir.
+type Options struct {
+	// Sort is sort key, SortName by default
+	Sort string `json:"sort"`
+	// Desc sorts in descending order
+	Desc bool `json:"desc"`
+	// HideDotfiles leaves out hidden items
+	HideDotfiles bool `json:"hideDotfiles"`
+	// Offset and Limit select a page of items,
+	// Limit 0 returns all items after Offset
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
 // Dir is directory struct.
 type Dir struct {
 	Path     string `json:"path"`
 	Parent   string `json:"parent"`
 	Contents []Item `json:"contents"`
+	// Total is number of items before paging
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
 }
 
 // ScanDir scans given directory and returns Dir struct.
-func ScanDir(path string) (Dir, error) {
+// MIME types and child counts are resolved only for the returned page.
+// Returns config.ErrPathNotAllowed for directories outside allowed roots.
+func ScanDir(path string, opts Options) (Dir, error) {
 	d := Dir{
 		Path:   path,
 		Parent: filepath.Dir(path),
+		Offset: opts.Offset,
+	}
+	by, err := sorter(opts.Sort, opts.Desc)
+	if err != nil {
+		return d, err
+	}
+	if err := config.CheckPath(path); err != nil {
+		return d, err
 	}
 
 	infos, err := ioutil.ReadDir(path)
@@ -85,24 +148,139 @@ func ScanDir(path string) (Dir, error) {
 	}
 
 	for _, info := range infos {
-		t := ItemFile
-		if info.IsDir() {
-			t = ItemDir
-		}
-		i := Item{
-			Name: info.Name(),
-			Path: filepath.Join(path, info.Name()),
-			Size: int(info.Size()),
-			Type: t,
+		i := newItem(path, info)
+		if opts.HideDotfiles && i.Hidden {
+			continue
 		}
 		d.Contents = append(d.Contents, i)
 	}
+	by.Sort(d.Contents)
+
+	d.Total = len(d.Contents)
+	d.Contents = page(d.Contents, opts.Offset, opts.Limit)
+	for idx := range d.Contents {
+		d.Contents[idx].resolve()
+	}
+	return d, nil
+}
+
+// newItem creates Item from file info. Symlinks to directories
+// are directories.
+func newItem(dir string, info os.FileInfo) Item {
+	i := Item{
+		Name:     info.Name(),
+		Path:     filepath.Join(dir, info.Name()),
+		Size:     info.Size(),
+		Type:     ItemFile,
+		Modified: info.ModTime(),
+		Mode:     info.Mode().String(),
+		Perm:     uint32(info.Mode().Perm()),
+		Hidden:   isHidden(info),
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		i.Target, _ = os.Readlink(i.Path)
+		if fi, err := os.Stat(i.Path); err == nil && fi.IsDir() {
+			i.Type = ItemDir
+		}
+	}
+	if info.IsDir() {
+		i.Type = ItemDir
+	}
+	return i
+}
 
-	// sort d.Contents by name
+// resolve sets MIME type and child count, which need reading the
+// file system. Items outside allowed roots, like symlinks pointing
+// out of them, are not read.
+func (i *Item) resolve() {
+	if i.Type == ItemDir {
+		i.MIME = dirMIMEType
+	}
+	if config.CheckPath(i.Path) != nil {
+		return
+	}
+	if i.Type == ItemDir {
+		if fh, err := os.Open(i.Path); err == nil {
+			names, _ := fh.Readdirnames(-1)
+			i.Children = len(names)
+			fh.Close()
+		}
+		return
+	}
+	i.MIME = mime.TypeByExtension(filepath.Ext(i.Name))
+	if i.MIME == "" {
+		i.MIME = sniffMIME(i.Path)
+	}
+}
+
+// sniffMIME detects MIME type from beginning of file.
+func sniffMIME(path string) string {
+	fh, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer fh.Close()
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(fh, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// sorter returns sort function for sort key. Ties are sorted by name.
+func sorter(key string, desc bool) (By, error) {
 	name := func(i1, i2 *Item) bool {
 		return strings.ToLower(i1.Name) < strings.ToLower(i2.Name)
 	}
-	By(name).Sort(d.Contents)
+	var by By
+	switch key {
+	case "", SortName:
+		by = name
+	case SortSize:
+		by = func(i1, i2 *Item) bool {
+			if i1.Size != i2.Size {
+				return i1.Size < i2.Size
+			}
+			return name(i1, i2)
+		}
+	case SortModified:
+		by = func(i1, i2 *Item) bool {
+			if !i1.Modified.Equal(i2.Modified) {
+				return i1.Modified.Before(i2.Modified)
+			}
+			return name(i1, i2)
+		}
+	case SortType:
+		by = func(i1, i2 *Item) bool {
+			if i1.Type != i2.Type {
+				return i1.Type == ItemDir
+			}
+			return name(i1, i2)
+		}
+	default:
+		return nil, ErrUnknownSort
+	}
+	if desc {
+		asc := by
+		by = func(i1, i2 *Item) bool {
+			return asc(i2, i1)
+		}
+	}
+	return by, nil
+}
 
-	return d, nil
+// page returns items from offset, at most limit items if limit > 0.
+func page(items []Item, offset int, limit int) []Item {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
 }
@@ -0,0 +1,125 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/config"
+)
+
+func TestScanDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filemaps-browse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"b.txt":   "bb",
+		"A.go":    "package a\n",
+		"c.bin":   "ccc",
+		".hidden": "",
+	}
+	now := time.Now()
+	for name, content := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+	}
+	os.Chtimes(filepath.Join(dir, "c.bin"), now.Add(-time.Hour), now.Add(-time.Hour))
+	os.MkdirAll(filepath.Join(dir, "sub", "x"), 0700)
+
+	tests := []struct {
+		opts     Options
+		expected []string
+	}{
+		{Options{}, []string{".hidden", "A.go", "b.txt", "c.bin", "sub"}},
+		{Options{HideDotfiles: true, Desc: true}, []string{"sub", "c.bin", "b.txt", "A.go"}},
+		{Options{Sort: SortSize, HideDotfiles: true}, []string{"b.txt", "c.bin", "A.go"}},
+		{Options{Sort: SortType, Offset: 1, Limit: 2}, []string{".hidden", "A.go"}},
+		{Options{Offset: 10}, []string{}},
+	}
+	for _, test := range tests {
+		d, err := ScanDir(dir, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, i := range d.Contents {
+			// directory sizes depend on file system
+			if test.opts.Sort == SortSize && i.Type == ItemDir {
+				continue
+			}
+			names = append(names, i.Name)
+		}
+		if len(names) != len(test.expected) {
+			t.Errorf("%+v: expected %v, got %v", test.opts, test.expected, names)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.expected[i] {
+				t.Errorf("%+v: expected %v, got %v", test.opts, test.expected, names)
+				break
+			}
+		}
+	}
+
+	d, _ := ScanDir(dir, Options{Sort: SortType, Limit: 1})
+	if d.Total != 5 {
+		t.Errorf("Expected total 5, got %d", d.Total)
+	}
+	if sub := d.Contents[0]; sub.Type != ItemDir || sub.Children != 1 || sub.MIME != dirMIMEType {
+		t.Errorf("Unexpected directory item %+v", sub)
+	}
+
+	if _, err := ScanDir(dir, Options{Sort: "color"}); err != ErrUnknownSort {
+		t.Error("Expected ErrUnknownSort, got", err)
+	}
+}
+
+func TestScanDirAllowedRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filemaps-browse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outside, err := ioutil.TempDir("", "filemaps-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("%PDF-1.4"), 0600)
+	if err := os.Symlink(outside, filepath.Join(dir, "out")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "secret"))
+
+	data, _ := json.Marshal(config.Configuration{AllowedRoots: dir})
+	if _, err := config.ParseJSON(bytes.NewBuffer(data)); err != nil {
+		t.Fatal(err)
+	}
+	defer config.ParseJSON(bytes.NewBufferString("{}"))
+
+	d, err := ScanDir(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Contents) != 2 {
+		t.Fatalf("Expected 2 items, got %+v", d.Contents)
+	}
+	for _, i := range d.Contents {
+		if i.Children != 0 || (i.Type == ItemFile && i.MIME != "") {
+			t.Errorf("Expected item outside allowed roots not to be read, got %+v", i)
+		}
+	}
+	if _, err := ScanDir(outside, Options{}); err != config.ErrPathNotAllowed {
+		t.Error("Expected ErrPathNotAllowed, got", err)
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package browser
+
+import (
+	"os"
+	"strings"
+)
+
+// isHidden tells if file is a dotfile.
+func isHidden(info os.FileInfo) bool {
+	return strings.HasPrefix(info.Name(), ".")
+}
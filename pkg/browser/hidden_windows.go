@@ -0,0 +1,25 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package browser
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// isHidden tells if file is a dotfile or has the hidden attribute.
+func isHidden(info os.FileInfo) bool {
+	if strings.HasPrefix(info.Name(), ".") {
+		return true
+	}
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return d.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+	}
+	return false
+}
@@ -32,6 +32,9 @@ type Configuration struct {
 	TextEditor           string `json:"textEditor"`
 	TextEditorCustom1Cmd string `json:"textEditorCustom1Cmd"`
 	TrustedAddresses     string `json:"trustedAddresses"`
+	// AllowedRoots is comma separated list of directories which can
+	// be browsed, scanned and opened. Empty allows all directories.
+	AllowedRoots string `json:"allowedRoots"`
 }
 
 // CreateConfiguration creates Configuration singleton instance.
@@ -6,6 +6,9 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -24,3 +27,45 @@ func TestReadWrite(t *testing.T) {
 func getTestPath() string {
 	return "/tmp/filemaps.config"
 }
+
+func TestCheckPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filemaps-roots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	allowed := filepath.Join(dir, "allowed")
+	secret := filepath.Join(dir, "secret")
+	os.MkdirAll(filepath.Join(allowed, "sub"), 0700)
+	os.MkdirAll(secret, 0700)
+	os.Symlink(secret, filepath.Join(allowed, "link"))
+
+	old := cfg
+	defer func() { cfg = old }()
+	cfg = &Configuration{AllowedRoots: " " + allowed + ", /nonexistent-root"}
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{allowed, true},
+		{filepath.Join(allowed, "sub"), true},
+		{filepath.Join(allowed, "sub", "new.txt"), true},
+		{filepath.Join(allowed, "sub", "..", "..", "secret"), false},
+		{filepath.Join(allowed, "link"), false},
+		{filepath.Join(allowed, "link", "file.txt"), false},
+		{allowed + "-other", false},
+		{secret, false},
+	}
+	for _, test := range tests {
+		err := CheckPath(test.path)
+		if (err == nil) != test.allowed {
+			t.Errorf("%s: expected allowed %v, got %v", test.path, test.allowed, err)
+		}
+	}
+
+	cfg = &Configuration{}
+	if err := CheckPath(secret); err != nil {
+		t.Error("Expected all paths allowed without roots, got", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathNotAllowed is returned for paths outside allowed roots.
+var ErrPathNotAllowed = errors.New("path not allowed")
+
+// GetAllowedRoots returns allowed root directories with symlinks
+// resolved. Empty slice means all directories are allowed.
+func (c *Configuration) GetAllowedRoots() []string {
+	var roots []string
+	for _, r := range strings.Split(c.AllowedRoots, ",") {
+		r = strings.TrimSpace(r)
+		if r != "" {
+			roots = append(roots, realPath(r))
+		}
+	}
+	return roots
+}
+
+// CheckPath returns ErrPathNotAllowed if path is not inside allowed
+// roots. Symlinks are resolved before the check, so links cannot point
+// outside the roots. All paths are allowed if no roots are configured
+// or configuration is not created.
+func CheckPath(path string) error {
+	if cfg == nil {
+		return nil
+	}
+	roots := cfg.GetAllowedRoots()
+	if len(roots) == 0 {
+		return nil
+	}
+	real := realPath(path)
+	for _, root := range roots {
+		if isInside(real, root) {
+			return nil
+		}
+	}
+	log.WithFields(log.Fields{
+		"path": path,
+		"real": real,
+	}).Warn("Path outside allowed roots")
+	return ErrPathNotAllowed
+}
+
+// realPath returns absolute path with symlinks resolved. Symlinks are
+// resolved in the nearest existing parent if path does not exist.
+func realPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = filepath.Clean(path)
+	}
+	rest := ""
+	for p := abs; ; {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(real, rest)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return abs
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// isInside tells if path is root or inside it.
+func isInside(path string, root string) bool {
+	if path == root {
+		return true
+	}
+	if !strings.HasSuffix(root, string(filepath.Separator)) {
+		root += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, root)
+}
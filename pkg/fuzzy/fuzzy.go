@@ -0,0 +1,166 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// Package fuzzy implements fuzzy matching of file paths.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 10
+	bonusConsecutive = 8
+	bonusBasename    = 4
+	// gaps between matches cost gapStartPenalty plus one per
+	// skipped rune, at most maxGapPenalty
+	gapStartPenalty = 3
+	maxGapPenalty   = 10
+)
+
+// Match is a fuzzy match of a pattern in a string.
+type Match struct {
+	Score int `json:"score"`
+	// Positions are indices of matched runes
+	Positions []int `json:"positions"`
+}
+
+// Find matches pattern in path. Pattern is split to terms by spaces
+// and all terms must match. Characters of a term must appear in order
+// but not necessarily next to each other. Matching is case-insensitive
+// unless the term contains upper case letters. Matches at word
+// boundaries, consecutive matches and matches in the file name
+// score higher.
+func Find(pattern string, path string) (Match, bool) {
+	terms := strings.Fields(pattern)
+	if len(terms) == 0 {
+		return Match{}, false
+	}
+	text := []rune(path)
+	lower := make([]rune, len(text))
+	base := 0
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+		if r == '/' || r == '\\' {
+			base = i + 1
+		}
+	}
+
+	var m Match
+	for _, term := range terms {
+		tm, ok := findTerm([]rune(term), text, lower, base)
+		if !ok {
+			return Match{}, false
+		}
+		m.Score += tm.Score
+		m.Positions = append(m.Positions, tm.Positions...)
+	}
+	m.Positions = unique(m.Positions)
+	return m, true
+}
+
+// findTerm matches term greedily from each possible start
+// and returns the best match.
+func findTerm(term []rune, text []rune, lower []rune, base int) (Match, bool) {
+	hay := text
+	if !hasUpper(term) {
+		hay = lower
+		for i, r := range term {
+			term[i] = unicode.ToLower(r)
+		}
+	}
+
+	best := Match{}
+	found := false
+	for start := range hay {
+		if hay[start] != term[0] {
+			continue
+		}
+		positions := []int{start}
+		i := start + 1
+		for _, r := range term[1:] {
+			for i < len(hay) && hay[i] != r {
+				i++
+			}
+			if i == len(hay) {
+				break
+			}
+			positions = append(positions, i)
+			i++
+		}
+		if len(positions) < len(term) {
+			// later starts cannot match either
+			break
+		}
+		score := scorePositions(positions, text, base)
+		if !found || score > best.Score {
+			best = Match{Score: score, Positions: positions}
+			found = true
+		}
+	}
+	return best, found
+}
+
+func scorePositions(positions []int, text []rune, base int) int {
+	score := 0
+	for k, i := range positions {
+		score += scoreMatch
+		if isBoundary(text, i) {
+			score += bonusBoundary
+		}
+		if i >= base {
+			score += bonusBasename
+		}
+		if k > 0 {
+			gap := i - positions[k-1] - 1
+			if gap == 0 {
+				score += bonusConsecutive
+			} else if penalty := gapStartPenalty + gap - 1; penalty < maxGapPenalty {
+				score -= penalty
+			} else {
+				score -= maxGapPenalty
+			}
+		}
+	}
+	return score
+}
+
+// isBoundary tells if rune at i starts a word.
+func isBoundary(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := text[i-1], text[i]
+	switch prev {
+	case '/', '\\', '_', '-', '.', ' ':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+func hasUpper(term []rune) bool {
+	for _, r := range term {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// unique sorts positions and removes duplicates.
+func unique(positions []int) []int {
+	sort.Ints(positions)
+	n := 0
+	for i, p := range positions {
+		if i == 0 || p != positions[n-1] {
+			positions[n] = p
+			n++
+		}
+	}
+	return positions[:n]
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package fuzzy
+
+import (
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		path      string
+		ok        bool
+		positions []int
+	}{
+		{"main", "cmd/main.go", true, []int{4, 5, 6, 7}},
+		{"mgo", "cmd/main.go", true, []int{4, 9, 10}},
+		{"MAIN", "cmd/main.go", false, nil},
+		{"Main", "cmd/Main.go", true, []int{4, 5, 6, 7}},
+		{"cmd go", "cmd/main.go", true, []int{0, 1, 2, 9, 10}},
+		{"cmd xyz", "cmd/main.go", false, nil},
+		{"", "cmd/main.go", false, nil},
+		{"niam", "cmd/main.go", false, nil},
+	}
+	for _, test := range tests {
+		m, ok := Find(test.pattern, test.path)
+		if ok != test.ok {
+			t.Errorf("%q in %q: expected %v, got %v", test.pattern, test.path, test.ok, ok)
+			continue
+		}
+		if len(m.Positions) != len(test.positions) {
+			t.Errorf("%q in %q: expected positions %v, got %v", test.pattern, test.path, test.positions, m.Positions)
+			continue
+		}
+		for i := range test.positions {
+			if m.Positions[i] != test.positions[i] {
+				t.Errorf("%q in %q: expected positions %v, got %v", test.pattern, test.path, test.positions, m.Positions)
+				break
+			}
+		}
+	}
+}
+
+func TestFindRanking(t *testing.T) {
+	// better match first
+	tests := [][3]string{
+		{"main", "cmd/main.go", "pkg/model/map_index.go"},
+		{"sr", "pkg/httpd/scanroutes.go", "pkg/scanner/ignore.go"},
+		{"proxy", "pkg/model/proxymap.go", "pkg/p/r/o/x/y.go"},
+		{"map", "pkg/model/map.go", "pkg/model/mapmanager/test.go"},
+	}
+	for _, test := range tests {
+		a, okA := Find(test[0], test[1])
+		b, okB := Find(test[0], test[2])
+		if !okA || !okB {
+			t.Errorf("%q: expected both to match", test[0])
+			continue
+		}
+		if a.Score <= b.Score {
+			t.Errorf("%q: expected %s (%d) to score higher than %s (%d)", test[0], test[1], a.Score, test[2], b.Score)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+
+	"github.com/filemaps/filemaps/pkg/model"
+)
+
+// AlignResources aligns given resources to left, right, top, bottom,
+// center or middle.
+func AlignResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		IDs  []model.ResourceID `json:"ids"`
+		Mode string             `json:"mode"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	mode, err := model.ParseAlignMode(jr.Mode)
+	if err != nil {
+		WriteJSONError(w, 400, "unknown align mode")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"ids":  jr.IDs,
+		"mode": mode,
+	}).Info("Align Resources")
+
+	skipped, err := pm.AlignResources(jr.IDs, mode)
+	if err != nil {
+		WriteJSONError(w, 404, err.Error())
+		return
+	}
+	writeArrangedResources(w, pm, jr.IDs, skipped)
+}
+
+// DistributeResources spaces given resources evenly.
+func DistributeResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		IDs  []model.ResourceID `json:"ids"`
+		Axis string             `json:"axis"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	axis, err := model.ParseAxis(jr.Axis)
+	if err != nil {
+		WriteJSONError(w, 400, "unknown axis")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"ids":  jr.IDs,
+		"axis": axis,
+	}).Info("Distribute Resources")
+
+	skipped, err := pm.DistributeResources(jr.IDs, axis)
+	if err != nil {
+		WriteJSONError(w, 404, err.Error())
+		return
+	}
+	writeArrangedResources(w, pm, jr.IDs, skipped)
+}
+
+// SnapResources moves given resources to the map grid.
+// If grid size is given, it is stored to the map first.
+func SnapResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		IDs      []model.ResourceID `json:"ids"`
+		GridSize float64            `json:"gridSize"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil || jr.GridSize < 0 {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"ids":      jr.IDs,
+		"gridSize": jr.GridSize,
+	}).Info("Snap Resources")
+
+	pm.Read()
+	// check resources before changing grid size of the map
+	for _, id := range jr.IDs {
+		if pm.GetResource(id) == nil {
+			WriteJSONError(w, 404, fmt.Sprintf("resource %d not found", id))
+			return
+		}
+	}
+	if jr.GridSize > 0 {
+		pm.GridSize = jr.GridSize
+		pm.Changed = true
+	}
+	skipped, err := pm.SnapResources(jr.IDs)
+	if err != nil {
+		WriteJSONError(w, 404, err.Error())
+		return
+	}
+	writeArrangedResources(w, pm, jr.IDs, skipped)
+}
+
+// ArrangeResponse is struct used for JSON response of arrange actions.
+// Skipped are pinned resources which were not moved.
+type ArrangeResponse struct {
+	Resources []model.StyledResource `json:"resources"`
+	Skipped   []model.ResourceID     `json:"skipped"`
+}
+
+func writeArrangedResources(w http.ResponseWriter, pm *model.ProxyMap, ids []model.ResourceID, skipped []model.ResourceID) {
+	pm.Write()
+	var rsrcs []*model.Resource
+	for _, id := range ids {
+		if rsrc := pm.GetResource(id); rsrc != nil {
+			rsrcs = append(rsrcs, rsrc)
+		}
+	}
+	WriteJSON(w, ArrangeResponse{
+		Resources: pm.StyledResources(rsrcs),
+		Skipped:   skipped,
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 
 	"github.com/filemaps/filemaps/pkg/browser"
+	"github.com/filemaps/filemaps/pkg/config"
 )
 
 func routeBrowse(r *httprouter.Router) {
@@ -23,6 +24,7 @@ func routeBrowse(r *httprouter.Router) {
 func Browse(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	type JSONRequest struct {
 		Path string `json:"path"`
+		browser.Options
 	}
 	var jr JSONRequest
 	d := json.NewDecoder(r.Body)
@@ -34,7 +36,11 @@ func Browse(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	}
 
 	log.WithFields(log.Fields{
-		"path": jr.Path,
+		"path":   jr.Path,
+		"sort":   jr.Sort,
+		"desc":   jr.Desc,
+		"offset": jr.Offset,
+		"limit":  jr.Limit,
 	}).Info("Browse")
 
 	if jr.Path == "" {
@@ -42,7 +48,20 @@ func Browse(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	dir, err := browser.ScanDir(jr.Path)
+	if jr.Offset < 0 || jr.Limit < 0 {
+		WriteJSONError(w, 400, "invalid page")
+		return
+	}
+
+	dir, err := browser.ScanDir(jr.Path, jr.Options)
+	if err == browser.ErrUnknownSort {
+		WriteJSONError(w, 400, err.Error())
+		return
+	}
+	if err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
 	if err != nil {
 		log.WithFields(log.Fields{
 			"path": jr.Path,
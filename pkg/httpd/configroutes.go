@@ -28,16 +28,21 @@ func ReadConfig(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	WriteJSON(w, resp)
 }
 
-// ConfigCtrl is controller for configuration.
+// WriteConfig is controller for updating configuration.
+// AllowedRoots cannot be changed through the API, as it limits what
+// the API can access.
 func WriteConfig(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	cfg := config.GetConfiguration()
+	updated := *cfg
 	d := json.NewDecoder(r.Body)
-	err := d.Decode(cfg)
+	err := d.Decode(&updated)
 	r.Body.Close()
 	if err != nil {
 		WriteJSONError(w, 400, "bad request")
 		return
 	}
+	updated.AllowedRoots = cfg.AllowedRoots
+	*cfg = updated
 
 	err = cfg.Write()
 	if err != nil {
@@ -45,5 +50,5 @@ func WriteConfig(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
-	fmt.Println("{}")
+	fmt.Fprint(w, "{}")
 }
@@ -31,6 +31,9 @@ func RunHTTP(addr string, webUIPath string) {
 
 	handler = router
 
+	// requests to a map are serialized
+	handler = mapLockMiddleware(handler)
+
 	// CORS middleware
 	if CORSEnabled {
 		corsHandler := cors.New(cors.Options{
@@ -51,20 +54,26 @@ func RunHTTP(addr string, webUIPath string) {
 
 // WriteJSON writes JSON response
 func WriteJSON(w http.ResponseWriter, v interface{}) error {
+	return WriteJSONStatus(w, 200, v)
+}
+
+// WriteJSONStatus writes JSON response with given status code.
+// Content type is set before the status is written.
+func WriteJSONStatus(w http.ResponseWriter, code int, v interface{}) error {
 	b, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
 	w.Write(b)
 	return nil
 }
 
 // WriteJSONError writes error JSON response
 func WriteJSONError(w http.ResponseWriter, code int, err string) {
-	w.WriteHeader(code)
-	WriteJSON(w, map[string]string{
+	WriteJSONStatus(w, code, map[string]string{
 		"error": err,
 	})
 }
@@ -93,6 +102,22 @@ func authMiddleware(handler http.Handler) http.Handler {
 	})
 }
 
+// mapLockMiddleware locks the map for the duration of requests to map
+// URLs, so requests and background jobs do not modify it concurrently.
+func mapLockMiddleware(handler http.Handler) http.Handler {
+	mapsPath := APIURL + "/maps/"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, mapsPath) {
+			id := strings.SplitN(r.URL.Path[len(mapsPath):], "/", 2)[0]
+			if pm := findProxyMap(id); pm != nil {
+				pm.Lock()
+				defer pm.Unlock()
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // addrIsTrusted returns true if given address is trusted.
 // addr is request.RemoteAddr which has format IP:port
 func addrIsTrusted(addr string) bool {
@@ -0,0 +1,109 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package httpd
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+
+	"github.com/filemaps/filemaps/pkg/model"
+)
+
+func routeLayers(r *httprouter.Router, mapURL string) {
+	layersURL := mapURL + "/layers"
+	r.GET(layersURL, ReadLayers)
+	r.PUT(layersURL, UpdateLayers)
+	r.POST(layersURL, RelayoutLayers)
+}
+
+// ReadLayers is controller for getting resources grouped by Z coordinate.
+func ReadLayers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	writeLayers(w, pm)
+}
+
+// UpdateLayers moves given resources to given Z coordinate.
+func UpdateLayers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		IDs []model.ResourceID `json:"ids"`
+		Z   float64            `json:"z"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"ids": jr.IDs,
+		"z":   jr.Z,
+	}).Info("Update Layers")
+
+	if err = pm.SetLayer(jr.IDs, jr.Z); err != nil {
+		WriteJSONError(w, 404, err.Error())
+		return
+	}
+	writeUpdatedResources(w, pm, jr.IDs)
+}
+
+// RelayoutLayers changes layer mode of the map and reassigns
+// Z coordinate for all resources.
+func RelayoutLayers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		Mode string `json:"mode"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	mode, err := model.ParseLayerMode(jr.Mode)
+	if err != nil {
+		WriteJSONError(w, 400, "unknown layer mode")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"mode": mode,
+	}).Info("Relayout Layers")
+
+	pm.Relayer(mode)
+	pm.Write()
+
+	writeLayers(w, pm)
+}
+
+// writeLayers writes layer mode and layers of ProxyMap to JSON response.
+func writeLayers(w http.ResponseWriter, pm *model.ProxyMap) {
+	resp := make(map[string]interface{})
+	resp["layers"] = pm.GetLayers()
+	resp["mode"] = pm.LayerMode.String()
+	WriteJSON(w, resp)
+}
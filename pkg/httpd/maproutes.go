@@ -11,9 +11,11 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"time"
 
+	"github.com/filemaps/filemaps/pkg/config"
 	"github.com/filemaps/filemaps/pkg/model"
 )
 
@@ -29,6 +31,11 @@ func routeMaps(r *httprouter.Router) {
 	r.POST(mapURL, ImportMap)
 
 	routeResources(r, mapURL)
+	routeLayers(r, mapURL)
+	routeStyles(r, mapURL)
+	routeMapTheme(r, mapURL)
+	routeMapTemplate(r, mapURL)
+	routeMapScans(r, mapURL)
 }
 
 // ReadMaps is controller for getting maps.
@@ -66,6 +73,21 @@ func CreateMap(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		"template":    jr.Template,
 	}).Info("Create Map")
 
+	for _, path := range []string{jr.Base, filepath.Join(jr.Base, jr.File)} {
+		if err := config.CheckPath(path); err != nil {
+			WriteJSONError(w, 403, err.Error())
+			return
+		}
+	}
+
+	var tmpl *model.Template
+	if jr.Template != "" {
+		if tmpl = model.GetTemplate(jr.Template); tmpl == nil {
+			WriteJSONError(w, 400, "template not found")
+			return
+		}
+	}
+
 	info := model.MapInfo{
 		Title:  jr.Title,
 		Base:   jr.Base,
@@ -79,6 +101,9 @@ func CreateMap(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 	pm.Description = jr.Description
+	if tmpl != nil {
+		tmpl.Apply(pm)
+	}
 	if err = pm.Write(); err != nil {
 		log.WithFields(log.Fields{
 			"err": err,
@@ -113,6 +138,11 @@ func ImportMap(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		"path": jr.Path,
 	}).Info("Import Map")
 
+	if err := config.CheckPath(jr.Path); err != nil {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
+
 	mm := model.GetMapManager()
 	pm, err := mm.ImportMap(jr.Path)
 	if err != nil {
@@ -208,15 +238,29 @@ func findProxyMap(param string) *model.ProxyMap {
 	return pm
 }
 
+// mapResponse is Map with resolved styles in resources.
+type mapResponse struct {
+	*model.Map
+	Resources []model.StyledResource `json:"resources"`
+}
+
 // writeMap writes ProxyMap to JSON response.
 func writeMap(w http.ResponseWriter, pm *model.ProxyMap) {
 	if pm != nil {
 		pm.Read()
-		m := pm.Map
+		m := mapResponse{
+			Map:       pm.Map,
+			Resources: pm.StyledResources(pm.Resources),
+		}
 
 		resp := make(map[string]interface{})
 		resp["fileMap"] = m
-		resp["defaultStyles"] = model.NewDefaultStyles()
+		theme := pm.GetTheme()
+		resp["defaultStyles"] = theme.Styles
+		resp["theme"] = theme
+		if len(pm.Rejected) > 0 {
+			resp["rejectedStyles"] = pm.Rejected
+		}
 		WriteJSON(w, resp)
 	} else {
 		WriteJSONError(w, 404, "map not found")
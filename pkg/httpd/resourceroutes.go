@@ -10,14 +10,14 @@ import (
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
-	"math/rand"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
-	"time"
 
+	"github.com/filemaps/filemaps/pkg/config"
 	"github.com/filemaps/filemaps/pkg/model"
-	"github.com/filemaps/filemaps/pkg/scanner"
+	"github.com/filemaps/filemaps/pkg/preview"
 )
 
 func routeResources(r *httprouter.Router, mapURL string) {
@@ -29,10 +29,11 @@ func routeResources(r *httprouter.Router, mapURL string) {
 	r.GET(resourceURL, ReadResource)
 	// DELETE with JSON request body is problematic,
 	// using POST for multi-delete
-	r.POST(resourceURL, ScanOrDeleteResources)
+	r.POST(resourceURL, ResourcesAction)
 	r.DELETE(resourceURL, DeleteResource)
 
 	r.GET(resourceURL+"/open", OpenResource)
+	r.GET(resourceURL+"/preview", PreviewResource)
 }
 
 // CreateResources creates new Resources.
@@ -62,20 +63,35 @@ func CreateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		"items": jr.Items,
 	}).Info("Create Resources")
 
-	var ids []model.ResourceID
-	for _, item := range jr.Items {
-		// convert absolute path to relative
-		path, err := filepath.Rel(pm.Base, item.Path)
+	// convert paths to relative, paths relative to map base
+	// are accepted too
+	paths := make([]string, len(jr.Items))
+	for i, item := range jr.Items {
+		path := item.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(pm.Base, path)
+		}
+		if err := config.CheckPath(path); err != nil {
+			WriteJSONError(w, 403, err.Error())
+			return
+		}
+		rel, err := filepath.Rel(pm.Base, path)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"basepath": pm.Base,
-				"targpath": item.Path,
+				"targpath": path,
 			}).Error("Could not make relative path")
-			path = item.Path
+			WriteJSONError(w, 400, "invalid path "+item.Path)
+			return
 		}
+		paths[i] = rel
+	}
+
+	var ids []model.ResourceID
+	for i, item := range jr.Items {
 		rsrc := model.Resource{
 			Type: model.ResourceFile,
-			Path: path,
+			Path: paths[i],
 			Pos:  item.Pos,
 		}
 
@@ -116,8 +132,10 @@ func UpdateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 	}
 
 	type ResourceData struct {
-		ID  model.ResourceID `json:"id"`
-		Pos model.Position   `json:"pos"`
+		ID     model.ResourceID `json:"id"`
+		Pos    *model.Position  `json:"pos"`
+		Pinned *bool            `json:"pinned"`
+		Tags   []string         `json:"tags"`
 	}
 	type JSONRequest struct {
 		Resources []ResourceData `json:"resources"`
@@ -132,93 +150,72 @@ func UpdateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 	}
 
 	pm.Read()
-	var ids []model.ResourceID
-	for _, resData := range jr.Resources {
-		rsrc := pm.GetResource(resData.ID)
-		if rsrc == nil {
+	// look up all resources first, so nothing is changed if
+	// a resource is not found
+	rsrcs := make([]*model.Resource, len(jr.Resources))
+	for i, resData := range jr.Resources {
+		if rsrcs[i] = pm.GetResource(resData.ID); rsrcs[i] == nil {
 			WriteJSONError(w, 404, fmt.Sprintf("resource %d not found", resData.ID))
 			return
 		}
-		rsrc.Pos = resData.Pos
+	}
+	var ids []model.ResourceID
+	for i, resData := range jr.Resources {
+		rsrc := rsrcs[i]
+		if resData.Pos != nil {
+			rsrc.Pos = *resData.Pos
+		}
+		if resData.Pinned != nil {
+			rsrc.Pinned = *resData.Pinned
+		}
+		if resData.Tags != nil {
+			rsrc.Tags = resData.Tags
+		}
 		ids = append(ids, rsrc.ResourceID)
 	}
-	pm.Write()
-
-	writeResources(w, pm, ids)
+	writeUpdatedResources(w, pm, ids)
 }
 
-func ScanOrDeleteResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	if ps.ByName("rid") == "scan" {
+// ResourcesAction routes POST requests having action name in
+// place of resource ID.
+func ResourcesAction(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	switch ps.ByName("rid") {
+	case "scan":
 		ScanResources(w, r, ps)
-	} else {
+	case "layout":
+		LayoutResources(w, r, ps)
+	case "align":
+		AlignResources(w, r, ps)
+	case "distribute":
+		DistributeResources(w, r, ps)
+	case "snap":
+		SnapResources(w, r, ps)
+	default:
 		DeleteResources(w, r, ps)
 	}
 }
 
-// ScanResources creates new Resources.
-func ScanResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+// LayoutResources repositions all unpinned resources.
+// Pinned resources stay in place and are avoided.
+func LayoutResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	pm := findProxyMap(ps.ByName("mapid"))
 	if pm == nil {
 		WriteJSONError(w, 404, "map not found")
 		return
 	}
-
-	type JSONRequest struct {
-		Path    string   `json:"path"`
-		Exclude []string `json:"exclude"`
-	}
-	var jr JSONRequest
-	err := json.NewDecoder(r.Body).Decode(&jr)
 	r.Body.Close()
-	if err != nil {
-		WriteJSONError(w, 400, "bad request")
-		return
-	}
 
 	log.WithFields(log.Fields{
-		"path":    jr.Path,
-		"exclude": jr.Exclude,
-	}).Info("Scan Resources")
+		"mapid": pm.ID,
+	}).Info("Layout Resources")
 
-	pm.Read()
-
-	pm.Exclude = jr.Exclude
-	pm.Changed = true
+	pm.Relayout()
+	pm.Write()
 
-	files := scanner.Scan(jr.Path, pm.Base, jr.Exclude)
 	var ids []model.ResourceID
-	var rsrcs []*model.Resource
-	rndm := rand.New(rand.NewSource(time.Now().UnixNano()))
-	for _, path := range files {
-		// convert absolute path to relative
-		path, err := filepath.Rel(pm.Base, path)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"basepath": pm.Base,
-				"targpath": path,
-			}).Error("ScanResources: Could not make relative path")
-		}
-
-		// skip existing resources
-		exists := pm.GetResourceByPath(path)
-		if exists == nil {
-			// assign new position
-			rsrc := model.Resource{
-				Type: model.ResourceFile,
-				Path: path,
-				Pos:  model.Position{X: rndm.Float64()*3000 - 1500, Y: rndm.Float64()*3000 - 1500, Z: 5},
-			}
-
-			rID := pm.AddResource(&rsrc)
-			pm.AssignResourceStyle(&rsrc)
-
-			ids = append(ids, rID)
-			rsrcs = append(rsrcs, &rsrc)
-		}
+	for _, rsrc := range pm.Resources {
+		ids = append(ids, rsrc.ResourceID)
 	}
-	pm.AssignPositions(rsrcs)
-	pm.Write()
-
 	writeResources(w, pm, ids)
 }
 
@@ -290,38 +287,109 @@ func OpenResource(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	pm.Read()
 	rsrc := pm.GetResource(model.ResourceID(id))
-	if rsrc != nil {
-		pm.OpenResource(rsrc)
-	} else {
+	if rsrc == nil {
 		WriteJSONError(w, 404, "resource not found")
+		return
+	}
+	if err := pm.OpenResource(rsrc); err != nil {
+		WriteJSONError(w, 403, err.Error())
+		return
 	}
 
 	fmt.Fprint(w, "{}")
 }
 
-// ResourceResponse is struct used for JSON response.
+// PreviewResource is controller for previewing a resource. Query
+// parameter lines is number of lines of text files, and size is
+// maximum width and height of image thumbnails.
+func PreviewResource(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	id, err := strconv.Atoi(ps.ByName("rid"))
+	if err != nil {
+		WriteJSONError(w, 404, "resource not found")
+		return
+	}
+
+	var opts preview.Options
+	q := r.URL.Query()
+	if s := q.Get("lines"); s != "" {
+		if opts.Lines, err = strconv.Atoi(s); err != nil {
+			WriteJSONError(w, 400, "invalid lines")
+			return
+		}
+	}
+	if s := q.Get("size"); s != "" {
+		if opts.ThumbnailSize, err = strconv.Atoi(s); err != nil {
+			WriteJSONError(w, 400, "invalid size")
+			return
+		}
+	}
+
+	pm.Read()
+	rsrc := pm.GetResource(model.ResourceID(id))
+	if rsrc == nil {
+		WriteJSONError(w, 404, "resource not found")
+		return
+	}
+
+	p, err := pm.PreviewResource(rsrc, opts)
+	if err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
+	if os.IsNotExist(err) {
+		WriteJSONError(w, 404, "file not found")
+		return
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path": rsrc.Path,
+			"err":  err,
+		}).Error("Could not preview resource")
+		WriteJSONError(w, 500, "preview failed")
+		return
+	}
+	WriteJSON(w, p)
+}
+
+// ResourcesResponse is struct used for JSON response.
 type ResourcesResponse struct {
-	Resources []*model.Resource `json:"resources"`
+	Resources []model.StyledResource `json:"resources"`
 }
 
 func writeResource(w http.ResponseWriter, pm *model.ProxyMap, id model.ResourceID) {
 	rsrc := pm.GetResource(id)
 	if rsrc != nil {
-		WriteJSON(w, rsrc)
+		WriteJSON(w, pm.StyledResources([]*model.Resource{rsrc})[0])
 	} else {
 		WriteJSONError(w, 404, "resource not found")
 	}
 }
 
+// writeUpdatedResources stores ProxyMap and writes updated resources
+// to JSON response.
+func writeUpdatedResources(w http.ResponseWriter, pm *model.ProxyMap, ids []model.ResourceID) {
+	pm.Write()
+	writeResources(w, pm, ids)
+}
+
 func writeResources(w http.ResponseWriter, pm *model.ProxyMap, ids []model.ResourceID) {
-	resp := ResourcesResponse{}
+	var rsrcs []*model.Resource
 	for _, id := range ids {
 		rsrc := pm.GetResource(id)
 		if rsrc != nil {
-			resp.Resources = append(resp.Resources, rsrc)
+			rsrcs = append(rsrcs, rsrc)
 		} else {
 			WriteJSONError(w, 404, "resource not found")
 		}
 	}
+	resp := ResourcesResponse{
+		Resources: pm.StyledResources(rsrcs),
+	}
 	WriteJSON(w, resp)
 }
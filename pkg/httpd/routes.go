@@ -27,6 +27,9 @@ func route(r *httprouter.Router, webUIPath string) {
 	routeMaps(r)
 	routeBrowse(r)
 	routeConfig(r)
+	routeThemes(r)
+	routeTemplates(r)
+	routeScans(r)
 	routeWebUI(r, webUIPath)
 }
 
@@ -0,0 +1,433 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/config"
+	"github.com/filemaps/filemaps/pkg/model"
+	"github.com/filemaps/filemaps/pkg/scanner"
+	"github.com/filemaps/filemaps/pkg/search"
+)
+
+const (
+	// scanStreamInterval is interval of status events in scan stream.
+	scanStreamInterval = 500 * time.Millisecond
+	// maxFindLimit is maximum number of matches returned by find.
+	maxFindLimit = 1000
+	// maxSearchLimit is maximum number of lines returned by search.
+	maxSearchLimit = 1000
+)
+
+func routeScans(r *httprouter.Router) {
+	scanURL := APIURL + "/scans/:jobid"
+	r.GET(scanURL, ReadScanJob)
+	r.DELETE(scanURL, CancelScanJob)
+	r.GET(scanURL+"/stream", StreamScanJob)
+}
+
+func routeMapScans(r *httprouter.Router, mapURL string) {
+	r.POST(mapURL+"/scans", CreateScanJob)
+	r.POST(mapURL+"/scans/preview", PreviewScan)
+	r.PUT(mapURL+"/watch", WatchMap)
+	r.GET(mapURL+"/find", FindFiles)
+	r.GET(mapURL+"/search", SearchFiles)
+}
+
+// scanRequest is JSON request for scans. Options not given are read
+// from the map.
+type scanRequest struct {
+	Path           string   `json:"path"`
+	Exclude        []string `json:"exclude"`
+	IgnoreFiles    *bool    `json:"ignoreFiles"`
+	FollowSymlinks *bool    `json:"followSymlinks"`
+	MaxFiles       int      `json:"maxFiles"`
+	MaxDepth       int      `json:"maxDepth"`
+	Sync           bool     `json:"sync"`
+	Archive        bool     `json:"archive"`
+	// Filter replaces filter of the map if given
+	Filter *scanner.Filter `json:"filter"`
+	// Source is scanner.SourceGit or scanner.SourceFS
+	Source    string `json:"source"`
+	Untracked *bool  `json:"untracked"`
+}
+
+// ScanResponse is struct used for JSON response of scan.
+// Excluded tells which ignore file or exclude pattern excluded what.
+type ScanResponse struct {
+	Resources []model.StyledResource `json:"resources"`
+	Removed   []model.StaleResource  `json:"removed"`
+	Archived  []model.StaleResource  `json:"archived"`
+	Restored  []model.ResourceID     `json:"restored"`
+	Excluded  []scanner.Exclusion    `json:"excluded"`
+	Truncated bool                   `json:"truncated"`
+	Source    string                 `json:"source"`
+}
+
+// ScanResources scans files to the map and responds when the scan
+// is done.
+func ScanResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	opts, ok := readScanOptions(w, r, pm)
+	if !ok {
+		return
+	}
+
+	// scan is cancelled if client goes away
+	result, err := pm.Scan(r.Context(), opts)
+	if err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Scan cancelled")
+		WriteJSONError(w, 503, "scan cancelled")
+		return
+	}
+	pm.Write()
+
+	WriteJSON(w, ScanResponse{
+		Resources: pm.StyledResources(result.Resources),
+		Removed:   result.Removed,
+		Archived:  result.Archived,
+		Restored:  result.Restored,
+		Excluded:  result.Excluded,
+		Truncated: result.Truncated,
+		Source:    result.Source,
+	})
+}
+
+// PreviewScan scans files and responds with changes the scan would
+// make, without changing the map.
+func PreviewScan(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	opts, ok := readScanOptions(w, r, pm)
+	if !ok {
+		return
+	}
+
+	preview, err := pm.PreviewScan(r.Context(), opts)
+	if err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Scan preview cancelled")
+		WriteJSONError(w, 503, "scan cancelled")
+		return
+	}
+	WriteJSON(w, preview)
+}
+
+// CreateScanJob starts scanning files to the map in background.
+func CreateScanJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	opts, ok := readScanOptions(w, r, pm)
+	if !ok {
+		return
+	}
+
+	job, err := model.StartScanJob(pm, opts)
+	if err == model.ErrScanRunning {
+		WriteJSONError(w, 409, err.Error())
+		return
+	}
+	WriteJSONStatus(w, 202, job.Status())
+}
+
+// ReadScanJob is controller for polling status of a scan job.
+func ReadScanJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	job := model.GetScanJob(ps.ByName("jobid"))
+	if job == nil {
+		WriteJSONError(w, 404, "scan job not found")
+		return
+	}
+	WriteJSON(w, job.Status())
+}
+
+// CancelScanJob cancels a running scan job.
+func CancelScanJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	job := model.GetScanJob(ps.ByName("jobid"))
+	if job == nil {
+		WriteJSONError(w, 404, "scan job not found")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id": ps.ByName("jobid"),
+	}).Info("Cancel scan job")
+
+	job.Cancel()
+	<-job.Done()
+	WriteJSON(w, job.Status())
+}
+
+// StreamScanJob streams status of a scan job as server-sent events
+// until the job is finished.
+func StreamScanJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	job := model.GetScanJob(ps.ByName("jobid"))
+	if job == nil {
+		WriteJSONError(w, 404, "scan job not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteJSONError(w, 500, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(scanStreamInterval)
+	defer ticker.Stop()
+	for {
+		status := job.Status()
+		b, err := json.Marshal(status)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		flusher.Flush()
+		if status.State != model.ScanRunning {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-job.Done():
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// WatchMap enables or disables watch mode of a map. Files created
+// under map base are added to the new zone and removed files are
+// flagged as missing.
+func WatchMap(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		Watch bool `json:"watch"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":    pm.ID,
+		"watch": jr.Watch,
+	}).Info("Watch Map")
+
+	if err := pm.SetWatch(jr.Watch); err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	} else if err != nil {
+		WriteJSONError(w, 500, "could not watch map")
+		return
+	}
+	pm.Write()
+
+	writeMap(w, pm)
+}
+
+// FindFiles is controller for fuzzy finding files under map base.
+// Query parameter q is the query and limit is maximum number of
+// matches.
+func FindFiles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	limit := model.DefaultFindLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l < 0 || l > maxFindLimit {
+			WriteJSONError(w, 400, "invalid limit")
+			return
+		}
+		limit = l
+	}
+
+	log.WithFields(log.Fields{
+		"id":    pm.ID,
+		"q":     query,
+		"limit": limit,
+	}).Info("Find Files")
+
+	result, err := pm.Find(r.Context(), query, limit)
+	if err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		WriteJSONError(w, 503, "find cancelled")
+		return
+	}
+	WriteJSON(w, result)
+}
+
+// SearchFiles is controller for full-text search of files of the map.
+// Query parameter q is the query, regex and case tell if it is a
+// regular expression and case sensitive, index enables trigram index
+// and limit is maximum number of matching lines.
+func SearchFiles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	q := r.URL.Query()
+	opts := search.Options{
+		Query: q.Get("q"),
+		Limit: search.DefaultLimit,
+	}
+	var useIndex bool
+	for name, dst := range map[string]*bool{
+		"regex": &opts.Regex,
+		"case":  &opts.CaseSensitive,
+		"index": &useIndex,
+	} {
+		if s := q.Get(name); s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				WriteJSONError(w, 400, "invalid "+name)
+				return
+			}
+			*dst = b
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 || l > maxSearchLimit {
+			WriteJSONError(w, 400, "invalid limit")
+			return
+		}
+		opts.Limit = l
+	}
+	if _, err := search.Compile(opts); err != nil {
+		WriteJSONError(w, 400, err.Error())
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":    pm.ID,
+		"q":     opts.Query,
+		"regex": opts.Regex,
+		"case":  opts.CaseSensitive,
+		"index": useIndex,
+		"limit": opts.Limit,
+	}).Info("Search Files")
+
+	result, err := pm.Search(r.Context(), opts, useIndex)
+	if err == config.ErrPathNotAllowed {
+		WriteJSONError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		WriteJSONError(w, 503, "search cancelled")
+		return
+	}
+	WriteJSON(w, result)
+}
+
+// readScanOptions reads scan request and fills options not given
+// from the map. Writes error response and returns false if request
+// is invalid.
+func readScanOptions(w http.ResponseWriter, r *http.Request, pm *model.ProxyMap) (model.ScanOptions, bool) {
+	var jr scanRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return model.ScanOptions{}, false
+	}
+
+	log.WithFields(log.Fields{
+		"path":        jr.Path,
+		"exclude":     jr.Exclude,
+		"ignoreFiles": jr.IgnoreFiles,
+		"symlinks":    jr.FollowSymlinks,
+		"maxFiles":    jr.MaxFiles,
+		"maxDepth":    jr.MaxDepth,
+		"filter":      jr.Filter != nil,
+		"source":      jr.Source,
+		"sync":        jr.Sync,
+		"archive":     jr.Archive,
+	}).Info("Scan Resources")
+
+	opts := pm.GetScanOptions()
+	if jr.Path != "" {
+		opts.Path = jr.Path
+	}
+	if jr.Exclude != nil {
+		opts.Exclude = jr.Exclude
+	}
+	if jr.IgnoreFiles != nil {
+		opts.IgnoreFiles = *jr.IgnoreFiles
+	}
+	if jr.FollowSymlinks != nil {
+		opts.FollowSymlinks = *jr.FollowSymlinks
+	}
+	switch jr.Source {
+	case "":
+	case scanner.SourceFS, scanner.SourceGit:
+		opts.Source = jr.Source
+	default:
+		WriteJSONError(w, 400, "unknown scan source")
+		return model.ScanOptions{}, false
+	}
+	if jr.Untracked != nil {
+		opts.Untracked = *jr.Untracked
+	}
+	if jr.Filter != nil {
+		opts.Filter = *jr.Filter
+	}
+	opts.MaxFiles = jr.MaxFiles
+	opts.MaxDepth = jr.MaxDepth
+	opts.Sync = jr.Sync
+	opts.Archive = jr.Archive
+	return opts, true
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package httpd
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+
+	"github.com/filemaps/filemaps/pkg/model"
+)
+
+func routeStyles(r *httprouter.Router, mapURL string) {
+	stylesURL := mapURL + "/styles"
+	r.GET(stylesURL, ReadStyles)
+	r.POST(stylesURL, CreateStyle)
+
+	styleURL := stylesURL + "/:sclass"
+	r.PUT(styleURL, UpdateStyle)
+	r.DELETE(styleURL, DeleteStyle)
+
+	styleRulesURL := mapURL + "/stylerules"
+	r.GET(styleRulesURL, ReadStyleRules)
+	r.PUT(styleRulesURL, UpdateStyleRules)
+}
+
+// ReadStyles is controller for getting styles of a map.
+func ReadStyles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	pm.Read()
+	resp := make(map[string]interface{})
+	resp["styles"] = pm.Styles
+	WriteJSON(w, resp)
+}
+
+// CreateStyle adds new style to a map.
+func CreateStyle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	var s model.Style
+	err := json.NewDecoder(r.Body).Decode(&s)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"sClass": s.SClass,
+		"rules":  s.Rules,
+	}).Info("Create Style")
+
+	err = pm.AddStyle(s)
+	if err == model.ErrStyleExists {
+		WriteJSONError(w, 409, err.Error())
+		return
+	} else if err != nil {
+		writeStyleError(w, err)
+		return
+	}
+	pm.Write()
+
+	writeStyle(w, pm, s.SClass)
+}
+
+// UpdateStyle replaces rules of a map style.
+func UpdateStyle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		Rules map[string]string `json:"rules"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	s := model.Style{
+		SClass: ps.ByName("sclass"),
+		Rules:  jr.Rules,
+	}
+
+	log.WithFields(log.Fields{
+		"sClass": s.SClass,
+		"rules":  s.Rules,
+	}).Info("Update Style")
+
+	err = pm.UpdateStyle(s)
+	if err == model.ErrStyleNotFound {
+		WriteJSONError(w, 404, err.Error())
+		return
+	} else if err != nil {
+		writeStyleError(w, err)
+		return
+	}
+	pm.Write()
+
+	writeStyle(w, pm, s.SClass)
+}
+
+// DeleteStyle deletes a map style.
+func DeleteStyle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	sclass := ps.ByName("sclass")
+	log.WithFields(log.Fields{
+		"sClass": sclass,
+	}).Info("Delete Style")
+
+	if err := pm.DeleteStyle(sclass); err != nil {
+		WriteJSONError(w, 404, err.Error())
+		return
+	}
+	pm.Write()
+
+	writeStyle(w, pm, sclass)
+}
+
+// ReadStyleRules is controller for getting style rules of a map.
+func ReadStyleRules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	pm.Read()
+	writeStyleRules(w, pm)
+}
+
+// UpdateStyleRules replaces style rules of a map.
+func UpdateStyleRules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		StyleRules []model.StyleRule `json:"styleRules"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	for _, sr := range jr.StyleRules {
+		if err := sr.Validate(); err != nil {
+			writeStyleError(w, err)
+			return
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"styleRules": jr.StyleRules,
+	}).Info("Update Style Rules")
+
+	pm.Read()
+	pm.StyleRules = jr.StyleRules
+	if pm.StyleRules == nil {
+		pm.StyleRules = make([]model.StyleRule, 0)
+	}
+	pm.Changed = true
+	pm.Write()
+
+	writeStyleRules(w, pm)
+}
+
+// writeStyleRules writes style rules of ProxyMap to JSON response.
+func writeStyleRules(w http.ResponseWriter, pm *model.ProxyMap) {
+	resp := make(map[string]interface{})
+	resp["styleRules"] = pm.StyleRules
+	WriteJSON(w, resp)
+}
+
+// writeStyleError writes style validation error to JSON response.
+// Rejected style rules are listed in the response.
+func writeStyleError(w http.ResponseWriter, err error) {
+	serr, ok := err.(*model.StyleError)
+	if !ok {
+		WriteJSONError(w, 400, err.Error())
+		return
+	}
+	WriteJSONStatus(w, 400, map[string]interface{}{
+		"error":    "rejected style rules",
+		"rejected": serr.Rejected,
+	})
+}
+
+// writeStyle writes map style and resources using the style class
+// to JSON response, so clients can update resources immediately.
+func writeStyle(w http.ResponseWriter, pm *model.ProxyMap, sclass string) {
+	resp := make(map[string]interface{})
+	resp["style"] = pm.GetClassStyle(sclass)
+	resp["resources"] = pm.GetResourcesByClass(sclass)
+	WriteJSON(w, resp)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package httpd
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+
+	"github.com/filemaps/filemaps/pkg/model"
+)
+
+func routeTemplates(r *httprouter.Router) {
+	templatesURL := APIURL + "/templates"
+	r.GET(templatesURL, ReadTemplates)
+	r.GET(templatesURL+"/:templateid", ReadTemplate)
+}
+
+func routeMapTemplate(r *httprouter.Router, mapURL string) {
+	r.POST(mapURL+"/template", SaveTemplate)
+}
+
+// ReadTemplates is controller for getting all map templates.
+func ReadTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	resp := make(map[string]interface{})
+	resp["templates"] = model.GetTemplates()
+	WriteJSON(w, resp)
+}
+
+// ReadTemplate is controller for getting a map template.
+func ReadTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	t := model.GetTemplate(ps.ByName("templateid"))
+	if t == nil {
+		WriteJSONError(w, 404, "template not found")
+		return
+	}
+	WriteJSON(w, t)
+}
+
+// SaveTemplate stores structure of a map as a new template.
+func SaveTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		Placements bool   `json:"placements"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"mapId":      pm.ID,
+		"id":         jr.ID,
+		"name":       jr.Name,
+		"placements": jr.Placements,
+	}).Info("Save Template")
+
+	if model.GetTemplate(jr.ID) != nil {
+		WriteJSONError(w, 409, "template exists")
+		return
+	}
+
+	t := model.NewTemplateFromMap(pm, jr.ID, jr.Name, jr.Placements)
+	if err = t.Validate(); err != nil {
+		writeStyleError(w, err)
+		return
+	}
+	if err = t.Write(); err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Could not write template")
+		WriteJSONError(w, 500, "could not save template")
+		return
+	}
+	WriteJSON(w, t)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+
+	"github.com/filemaps/filemaps/pkg/model"
+)
+
+func routeThemes(r *httprouter.Router) {
+	themesURL := APIURL + "/themes"
+	r.GET(themesURL, ReadThemes)
+	r.POST(themesURL, ImportTheme)
+
+	themeURL := themesURL + "/:themeid"
+	r.GET(themeURL, ExportTheme)
+	r.DELETE(themeURL, DeleteTheme)
+}
+
+func routeMapTheme(r *httprouter.Router, mapURL string) {
+	r.PUT(mapURL+"/theme", SelectTheme)
+}
+
+// ReadThemes is controller for getting all themes.
+func ReadThemes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	resp := make(map[string]interface{})
+	resp["themes"] = model.GetThemes()
+	WriteJSON(w, resp)
+}
+
+// ImportTheme stores theme given as standalone theme JSON file.
+func ImportTheme(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	t, err := model.ParseTheme(r.Body)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":   t.ID,
+		"name": t.Name,
+	}).Info("Import Theme")
+
+	if err = t.Validate(); err != nil {
+		writeStyleError(w, err)
+		return
+	}
+	if err = t.Write(); err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("Could not write theme")
+		WriteJSONError(w, 500, "could not save theme")
+		return
+	}
+	WriteJSON(w, t)
+}
+
+// ExportTheme writes theme as standalone theme JSON file.
+func ExportTheme(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	t := model.GetTheme(ps.ByName("themeid"))
+	if t == nil {
+		WriteJSONError(w, 404, "theme not found")
+		return
+	}
+
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", t.ID+".json"))
+	}
+	WriteJSON(w, t)
+}
+
+// DeleteTheme is controller for deleting a stored theme.
+func DeleteTheme(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := ps.ByName("themeid")
+	if model.GetTheme(id) == nil {
+		WriteJSONError(w, 404, "theme not found")
+		return
+	}
+
+	if err := model.DeleteTheme(id); err != nil {
+		WriteJSONError(w, 400, err.Error())
+		return
+	}
+	fmt.Fprint(w, "{}")
+}
+
+// SelectTheme selects theme for a map.
+func SelectTheme(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pm := findProxyMap(ps.ByName("mapid"))
+	if pm == nil {
+		WriteJSONError(w, 404, "map not found")
+		return
+	}
+
+	type JSONRequest struct {
+		Theme string `json:"theme"`
+	}
+	var jr JSONRequest
+	err := json.NewDecoder(r.Body).Decode(&jr)
+	r.Body.Close()
+	if err != nil {
+		WriteJSONError(w, 400, "bad request")
+		return
+	}
+
+	if model.GetTheme(jr.Theme) == nil {
+		WriteJSONError(w, 404, "theme not found")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":    pm.ID,
+		"theme": jr.Theme,
+	}).Info("Select Theme")
+
+	pm.Read()
+	pm.Theme = jr.Theme
+	pm.Changed = true
+	pm.Write()
+
+	writeMap(w, pm)
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// Package ignore implements gitignore compatible path matching.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Pattern is a parsed gitignore pattern.
+type Pattern struct {
+	// Text is the pattern as written
+	Text string
+	// Negate is true for patterns starting with '!'
+	Negate bool
+	// DirOnly is true for patterns ending with '/'
+	DirOnly bool
+	// Source tells where the pattern was read from
+	Source string
+	// Line is line number of the pattern in Source
+	Line int
+	// Base is slash separated directory the pattern is relative to,
+	// empty for the root
+	Base string
+	// segments are slash separated parts of the pattern,
+	// "**" segments match any number of directories
+	segments []string
+}
+
+// ParsePattern parses one line of gitignore file.
+// Returns nil for blank lines and comments.
+func ParsePattern(line string) *Pattern {
+	text := strings.TrimSuffix(line, "\r")
+	if strings.HasPrefix(text, "#") {
+		return nil
+	}
+	text = trimTrailingSpaces(text)
+	if text == "" {
+		return nil
+	}
+
+	p := &Pattern{Text: text}
+	pattern := text
+	if pattern[0] == '!' {
+		p.Negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p.DirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	// pattern having separator at the beginning or middle is relative
+	// to the base, others match at any level
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return nil
+	}
+
+	if !anchored {
+		p.segments = append(p.segments, "**")
+	}
+	p.segments = append(p.segments, strings.Split(pattern, "/")...)
+	return p
+}
+
+// Match tells if slash separated path relative to the base matches
+// the pattern. Negation is not taken into account.
+func (p *Pattern) Match(path string, isDir bool) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+	if p.Base != "" {
+		if !strings.HasPrefix(path, p.Base+"/") {
+			return false
+		}
+		path = path[len(p.Base)+1:]
+	}
+	return matchSegments(p.segments, strings.Split(path, "/"))
+}
+
+// Matcher matches paths against a list of gitignore patterns.
+// Later patterns override earlier ones.
+type Matcher struct {
+	patterns []*Pattern
+}
+
+// NewMatcher creates Matcher from gitignore lines.
+func NewMatcher(lines []string) *Matcher {
+	m := &Matcher{}
+	for _, line := range lines {
+		m.Add(line)
+	}
+	return m
+}
+
+// Add appends gitignore line to the matcher.
+func (m *Matcher) Add(line string) {
+	if p := ParsePattern(line); p != nil {
+		m.patterns = append(m.patterns, p)
+	}
+}
+
+// AddSource appends gitignore lines read from source. Patterns are
+// relative to slash separated directory base.
+func (m *Matcher) AddSource(source string, base string, lines []string) {
+	for i, line := range lines {
+		if p := ParsePattern(line); p != nil {
+			p.Source = source
+			p.Line = i + 1
+			p.Base = base
+			m.patterns = append(m.patterns, p)
+		}
+	}
+}
+
+// AddFile appends patterns from gitignore file at path.
+// Source of the patterns is source.
+func (m *Matcher) AddFile(path string, source string, base string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	m.AddSource(source, base, lines)
+	return nil
+}
+
+// Len returns number of patterns in the matcher.
+func (m *Matcher) Len() int {
+	return len(m.patterns)
+}
+
+// Merge creates Matcher containing patterns of given matchers.
+// Patterns of later matchers override earlier ones.
+func Merge(matchers ...*Matcher) *Matcher {
+	merged := &Matcher{}
+	for _, m := range matchers {
+		if m != nil {
+			merged.patterns = append(merged.patterns, m.patterns...)
+		}
+	}
+	return merged
+}
+
+// Match returns the last pattern matching path, or nil if no pattern
+// matches. Parent directories of the path are not checked.
+func (m *Matcher) Match(path string, isDir bool) *Pattern {
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		if m.patterns[i].Match(path, isDir) {
+			return m.patterns[i]
+		}
+	}
+	return nil
+}
+
+// ExcludedBy returns pattern excluding slash separated path relative to
+// the base, or nil if path is not excluded. Like in git, a path cannot
+// be re-included if any of its parent directories is excluded.
+func (m *Matcher) ExcludedBy(path string, isDir bool) *Pattern {
+	path = strings.Trim(path, "/")
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		parent := strings.Join(segments[:i], "/")
+		if p := m.Match(parent, true); p != nil && !p.Negate {
+			return p
+		}
+	}
+	if p := m.Match(path, isDir); p != nil && !p.Negate {
+		return p
+	}
+	return nil
+}
+
+// Excluded tells if path is excluded.
+func (m *Matcher) Excluded(path string, isDir bool) bool {
+	return m.ExcludedBy(path, isDir) != nil
+}
+
+// trimTrailingSpaces removes trailing spaces not quoted with backslash.
+func trimTrailingSpaces(s string) string {
+	end := len(s)
+	for end > 0 && s[end-1] == ' ' {
+		// count backslashes before the space
+		n := 0
+		for i := end - 2; i >= 0 && s[i] == '\\'; i-- {
+			n++
+		}
+		if n%2 == 1 {
+			break
+		}
+		end--
+	}
+	return s[:end]
+}
+
+// matchSegments matches pattern segments to path segments.
+// Leading and middle "**" match zero or more segments,
+// trailing "**" matches one or more.
+func matchSegments(pattern []string, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(path) > 0
+		}
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || !matchGlob([]rune(pattern[0]), []rune(path[0])) {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// matchGlob matches a path segment to a glob supporting '*', '?',
+// character classes and backslash escapes.
+func matchGlob(pattern []rune, name []rune) bool {
+	px, nx := 0, 0
+	// position to retry from when the last '*' should match more
+	starPx, starNx := -1, -1
+	for px < len(pattern) || nx < len(name) {
+		if px < len(pattern) {
+			c := pattern[px]
+			switch c {
+			case '*':
+				starPx, starNx = px, nx
+				px++
+				continue
+			case '?':
+				if nx < len(name) {
+					px++
+					nx++
+					continue
+				}
+			case '[':
+				if nx < len(name) {
+					width, matched := matchClass(pattern[px:], name[nx])
+					if width < 0 {
+						// malformed class never matches
+						return false
+					}
+					if matched {
+						px += width
+						nx++
+						continue
+					}
+				}
+			default:
+				width := 1
+				if c == '\\' {
+					if px+1 == len(pattern) {
+						return false
+					}
+					c = pattern[px+1]
+					width = 2
+				}
+				if nx < len(name) && name[nx] == c {
+					px += width
+					nx++
+					continue
+				}
+			}
+		}
+		if starPx >= 0 && starNx < len(name) {
+			starNx++
+			px, nx = starPx+1, starNx
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// posixClasses are supported [:name:] character classes.
+var posixClasses = map[string]func(rune) bool{
+	"alnum": func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha": unicode.IsLetter,
+	"blank": func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl": unicode.IsControl,
+	"digit": unicode.IsDigit,
+	"graph": func(r rune) bool { return unicode.IsGraphic(r) && !unicode.IsSpace(r) },
+	"lower": unicode.IsLower,
+	"print": unicode.IsPrint,
+	"punct": unicode.IsPunct,
+	"space": unicode.IsSpace,
+	"upper": unicode.IsUpper,
+	"xdigit": func(r rune) bool {
+		return unicode.IsDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+	},
+}
+
+// matchClass matches rune to character class at the beginning of
+// pattern. Returns width of the class in pattern, or -1 if the class
+// is malformed.
+func matchClass(pattern []rune, r rune) (int, bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+	matched := false
+	for first := true; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == ']' && !first {
+			return i + 1, matched != negate
+		}
+		first = false
+
+		if c == '[' && i+1 < len(pattern) && pattern[i+1] == ':' {
+			end := strings.Index(string(pattern[i+2:]), ":]")
+			if end >= 0 {
+				name := []rune(string(pattern[i+2:])[:end])
+				fn, ok := posixClasses[string(name)]
+				if !ok {
+					return -1, false
+				}
+				if fn(r) {
+					matched = true
+				}
+				i += 2 + len(name) + 1
+				continue
+			}
+		}
+
+		if c == '\\' {
+			i++
+			if i == len(pattern) {
+				return -1, false
+			}
+			c = pattern[i]
+		}
+		lo, hi := c, c
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			i += 2
+			hi = pattern[i]
+			if hi == '\\' {
+				i++
+				if i == len(pattern) {
+					return -1, false
+				}
+				hi = pattern[i]
+			}
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+	return -1, false
+}
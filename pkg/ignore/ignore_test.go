@@ -0,0 +1,136 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package ignore
+
+import (
+	"testing"
+)
+
+// Examples are from git documentation of gitignore.
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		// "hello.*" matches any file or directory whose name begins
+		// with "hello."
+		{"hello.*", "hello.c", false, true},
+		{"hello.*", "a/hello.java", false, true},
+		{"hello.*", "hello", false, false},
+		// "foo/" matches a directory foo, but not a regular file foo
+		{"foo/", "foo", true, true},
+		{"foo/", "a/foo", true, true},
+		{"foo/", "foo", false, false},
+		// "doc/frotz/" matches doc/frotz directory, but not a/doc/frotz
+		{"doc/frotz/", "doc/frotz", true, true},
+		{"doc/frotz/", "a/doc/frotz", true, false},
+		{"frotz/", "a/frotz", true, true},
+		// "/bar" matches only at the base
+		{"/bar", "bar", false, true},
+		{"/bar", "a/bar", false, false},
+		// "foo/*" matches "foo/test.json" and "foo/bar" but not
+		// "foo/bar/hello.c"
+		{"foo/*", "foo/test.json", false, true},
+		{"foo/*", "foo/bar", true, true},
+		{"foo/*", "foo/bar/hello.c", false, false},
+		// "**/foo" matches file or directory "foo" anywhere
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", true, true},
+		// "**/foo/bar" matches "bar" anywhere directly under "foo"
+		{"**/foo/bar", "foo/bar", false, true},
+		{"**/foo/bar", "a/foo/bar", false, true},
+		{"**/foo/bar", "a/foo/x/bar", false, false},
+		// "abc/**" matches all files inside directory "abc"
+		{"abc/**", "abc/x", false, true},
+		{"abc/**", "abc/x/y/z", false, true},
+		{"abc/**", "abc", true, false},
+		// "a/**/b" matches "a/b", "a/x/b", "a/x/y/b"
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "b/a/b", false, false},
+		{"**/testdata/**", "pkg/model/testdata/file.json", false, true},
+		// other consecutive asterisks are regular asterisks
+		{"a**b", "axxb", false, true},
+		{"a**b", "ax/xb", false, false},
+		// "*" and "?" do not match "/"
+		{"a/*.c", "a/b/c.c", false, false},
+		{"a?b", "a/b", false, false},
+		{"a?b", "axb", false, true},
+		// character classes
+		{"[a-c]at", "bat", false, true},
+		{"[a-c]at", "rat", false, false},
+		{"[!a-c]at", "rat", false, true},
+		{"[^a-c]at", "bat", false, false},
+		{"[]]x", "]x", false, true},
+		{"[a-]x", "-x", false, true},
+		{"[[:digit:]]*", "1abc", false, true},
+		{"[[:digit:]]*", "abc", false, false},
+		{"[[:upper:][:digit:]]", "Q", false, true},
+		{"[abc", "a", false, false},
+		// escapes
+		{"\\!important!.txt", "!important!.txt", false, true},
+		{"\\#file", "#file", false, true},
+		{"\\*", "*", false, true},
+		{"\\*", "a", false, false},
+		{"trailing\\ ", "trailing ", false, true},
+		{"spaces   ", "spaces", false, true},
+	}
+	for _, test := range tests {
+		p := ParsePattern(test.pattern)
+		if p == nil {
+			t.Errorf("ParsePattern(%q) returned nil", test.pattern)
+			continue
+		}
+		if p.Match(test.path, test.isDir) != test.match {
+			t.Errorf("%q matching %q (dir %v) != %v", test.pattern, test.path, test.isDir, test.match)
+		}
+	}
+}
+
+func TestParsePatternSkipped(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/", "!"} {
+		if p := ParsePattern(line); p != nil {
+			t.Errorf("Expected %q to be skipped", line)
+		}
+	}
+	p := ParsePattern("!*.html")
+	if p == nil || !p.Negate {
+		t.Error("Expected negated pattern")
+	}
+}
+
+func TestMatcherExcluded(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		// later pattern overrides earlier
+		{[]string{"*.html", "!foo.html"}, "foo.html", false, false},
+		{[]string{"*.html", "!foo.html"}, "bar.html", false, true},
+		{[]string{"!foo.html", "*.html"}, "foo.html", false, true},
+		// exclude everything except directory foo/bar
+		{[]string{"/*", "!/foo", "/foo/*", "!/foo/bar"}, "foo/bar/x.c", false, false},
+		{[]string{"/*", "!/foo", "/foo/*", "!/foo/bar"}, "foo/baz", false, true},
+		{[]string{"/*", "!/foo", "/foo/*", "!/foo/bar"}, "other/x.c", false, true},
+		// file cannot be re-included if parent directory is excluded
+		{[]string{"build/", "!build/keep.txt"}, "build/keep.txt", false, true},
+		{[]string{"build/*", "!build/keep.txt"}, "build/keep.txt", false, false},
+		{[]string{"vendor"}, "vendor/a/b.go", false, true},
+		{[]string{"**/testdata/**"}, "pkg/testdata/a/b.json", false, true},
+		{[]string{"**/testdata/**"}, "pkg/testdata", true, false},
+	}
+	for _, test := range tests {
+		m := NewMatcher(test.lines)
+		if m.Excluded(test.path, test.isDir) != test.excluded {
+			t.Errorf("%q excluding %q != %v", test.lines, test.path, test.excluded)
+		}
+	}
+}
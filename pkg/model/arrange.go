@@ -0,0 +1,221 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultGridSize is grid size used for snapping when map does not
+	// define one.
+	DefaultGridSize = 25
+)
+
+// AlignMode defines how resources are aligned.
+type AlignMode int
+
+// Align mode enum
+const (
+	AlignLeft AlignMode = iota
+	AlignRight
+	AlignTop
+	AlignBottom
+	// AlignCenter aligns horizontal centers to a vertical line.
+	AlignCenter
+	// AlignMiddle aligns vertical centers to a horizontal line.
+	AlignMiddle
+)
+
+// Converts AlignMode to string
+func (a AlignMode) String() string {
+	switch a {
+	case AlignLeft:
+		return "left"
+	case AlignRight:
+		return "right"
+	case AlignTop:
+		return "top"
+	case AlignBottom:
+		return "bottom"
+	case AlignCenter:
+		return "center"
+	case AlignMiddle:
+		return "middle"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAlignMode converts string to AlignMode.
+func ParseAlignMode(s string) (AlignMode, error) {
+	for a := AlignLeft; a <= AlignMiddle; a++ {
+		if a.String() == s {
+			return a, nil
+		}
+	}
+	return AlignLeft, fmt.Errorf("Unknown align mode %q", s)
+}
+
+// Axis defines direction for distributing resources.
+type Axis int
+
+// Axis enum
+const (
+	AxisHorizontal Axis = iota
+	AxisVertical
+)
+
+// Converts Axis to string
+func (a Axis) String() string {
+	switch a {
+	case AxisHorizontal:
+		return "horizontal"
+	case AxisVertical:
+		return "vertical"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAxis converts string to Axis.
+func ParseAxis(s string) (Axis, error) {
+	switch s {
+	case "horizontal", "x":
+		return AxisHorizontal, nil
+	case "vertical", "y":
+		return AxisVertical, nil
+	}
+	return AxisHorizontal, fmt.Errorf("Unknown axis %q", s)
+}
+
+// AlignResources aligns given resources. Y axis grows upwards,
+// so top means the largest Y coordinate. Pinned resources are not
+// moved, their IDs are returned.
+func (p *ProxyMap) AlignResources(ids []ResourceID, mode AlignMode) ([]ResourceID, error) {
+	rsrcs, skipped, err := p.getMovableResources(ids)
+	if err != nil || len(rsrcs) == 0 {
+		return skipped, err
+	}
+
+	minX, maxX := rsrcs[0].Pos.X, rsrcs[0].Pos.X
+	minY, maxY := rsrcs[0].Pos.Y, rsrcs[0].Pos.Y
+	for _, rsrc := range rsrcs {
+		minX = math.Min(minX, rsrc.Pos.X)
+		maxX = math.Max(maxX, rsrc.Pos.X)
+		minY = math.Min(minY, rsrc.Pos.Y)
+		maxY = math.Max(maxY, rsrc.Pos.Y)
+	}
+
+	for _, rsrc := range rsrcs {
+		switch mode {
+		case AlignLeft:
+			rsrc.Pos.X = minX
+		case AlignRight:
+			rsrc.Pos.X = maxX
+		case AlignTop:
+			rsrc.Pos.Y = maxY
+		case AlignBottom:
+			rsrc.Pos.Y = minY
+		case AlignCenter:
+			rsrc.Pos.X = (minX + maxX) / 2
+		case AlignMiddle:
+			rsrc.Pos.Y = (minY + maxY) / 2
+		}
+	}
+	p.Changed = true
+	return skipped, nil
+}
+
+// DistributeResources spaces given resources evenly along axis.
+// The outermost resources stay in place. Pinned resources are not
+// moved, their IDs are returned.
+func (p *ProxyMap) DistributeResources(ids []ResourceID, axis Axis) ([]ResourceID, error) {
+	rsrcs, skipped, err := p.getMovableResources(ids)
+	if err != nil || len(rsrcs) < 3 {
+		return skipped, err
+	}
+
+	coord := func(r *Resource) *float64 {
+		if axis == AxisVertical {
+			return &r.Pos.Y
+		}
+		return &r.Pos.X
+	}
+	sort.SliceStable(rsrcs, func(i, j int) bool {
+		return *coord(rsrcs[i]) < *coord(rsrcs[j])
+	})
+
+	first := *coord(rsrcs[0])
+	step := (*coord(rsrcs[len(rsrcs)-1]) - first) / float64(len(rsrcs)-1)
+	for i, rsrc := range rsrcs {
+		*coord(rsrc) = first + float64(i)*step
+	}
+	p.Changed = true
+	return skipped, nil
+}
+
+// SnapResources moves given resources to the nearest grid point.
+// Pinned resources are not moved, their IDs are returned.
+func (p *ProxyMap) SnapResources(ids []ResourceID) ([]ResourceID, error) {
+	rsrcs, skipped, err := p.getMovableResources(ids)
+	if err != nil {
+		return skipped, err
+	}
+
+	size := p.GetGridSize()
+	for _, rsrc := range rsrcs {
+		rsrc.Pos.X = math.Floor(rsrc.Pos.X/size+0.5) * size
+		rsrc.Pos.Y = math.Floor(rsrc.Pos.Y/size+0.5) * size
+	}
+	p.Changed = true
+	return skipped, nil
+}
+
+// GetGridSize returns grid size of the map.
+func (p *ProxyMap) GetGridSize() float64 {
+	p.Read()
+	if p.GridSize <= 0 {
+		return DefaultGridSize
+	}
+	return p.GridSize
+}
+
+// getResources returns resources for given IDs or error if any of them
+// is not found.
+func (p *ProxyMap) getResources(ids []ResourceID) ([]*Resource, error) {
+	p.Read()
+	var rsrcs []*Resource
+	for _, id := range ids {
+		rsrc := p.GetResource(id)
+		if rsrc == nil {
+			return nil, fmt.Errorf("resource %d not found", id)
+		}
+		rsrcs = append(rsrcs, rsrc)
+	}
+	return rsrcs, nil
+}
+
+// getMovableResources returns unpinned resources for given IDs and
+// IDs of pinned resources, or error if any of them is not found.
+func (p *ProxyMap) getMovableResources(ids []ResourceID) ([]*Resource, []ResourceID, error) {
+	rsrcs, err := p.getResources(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var movable []*Resource
+	skipped := make([]ResourceID, 0)
+	for _, rsrc := range rsrcs {
+		if rsrc.Pinned {
+			skipped = append(skipped, rsrc.ResourceID)
+		} else {
+			movable = append(movable, rsrc)
+		}
+	}
+	return movable, skipped, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+// getTestArrangeMap returns map with resources at given X,Y positions.
+func getTestArrangeMap(positions [][2]float64) (*ProxyMap, []ResourceID) {
+	pm := getTestProxyMap()
+	var ids []ResourceID
+	for _, pos := range positions {
+		rsrc := &Resource{}
+		rsrc.Pos.X, rsrc.Pos.Y = pos[0], pos[1]
+		ids = append(ids, pm.AddResource(rsrc))
+	}
+	return pm, ids
+}
+
+func getTestPositions(pm *ProxyMap, ids []ResourceID) [][2]float64 {
+	var positions [][2]float64
+	for _, id := range ids {
+		rsrc := pm.GetResource(id)
+		positions = append(positions, [2]float64{rsrc.Pos.X, rsrc.Pos.Y})
+	}
+	return positions
+}
+
+func equalPositions(a [][2]float64, b [][2]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAlignResources(t *testing.T) {
+	positions := [][2]float64{{0, 10}, {100, -50}, {40, 30}}
+	var tests = []struct {
+		mode     AlignMode
+		expected [][2]float64
+	}{
+		{AlignLeft, [][2]float64{{0, 10}, {0, -50}, {0, 30}}},
+		{AlignRight, [][2]float64{{100, 10}, {100, -50}, {100, 30}}},
+		{AlignTop, [][2]float64{{0, 30}, {100, 30}, {40, 30}}},
+		{AlignBottom, [][2]float64{{0, -50}, {100, -50}, {40, -50}}},
+		{AlignCenter, [][2]float64{{50, 10}, {50, -50}, {50, 30}}},
+		{AlignMiddle, [][2]float64{{0, -10}, {100, -10}, {40, -10}}},
+	}
+	for _, test := range tests {
+		pm, ids := getTestArrangeMap(positions)
+		skipped, err := pm.AlignResources(ids, test.mode)
+		if err != nil || len(skipped) != 0 {
+			t.Fatal(skipped, err)
+		}
+		if got := getTestPositions(pm, ids); !equalPositions(got, test.expected) {
+			t.Errorf("Align %v: expected %v, got %v", test.mode, test.expected, got)
+		}
+	}
+}
+
+func TestDistributeResources(t *testing.T) {
+	var tests = []struct {
+		positions [][2]float64
+		axis      Axis
+		expected  [][2]float64
+	}{
+		{
+			[][2]float64{{0, 0}, {90, 5}, {10, 10}, {30, 20}},
+			AxisHorizontal,
+			[][2]float64{{0, 0}, {90, 5}, {30, 10}, {60, 20}},
+		},
+		{
+			[][2]float64{{5, 100}, {0, 0}, {10, 10}},
+			AxisVertical,
+			[][2]float64{{5, 100}, {0, 0}, {10, 50}},
+		},
+		// two resources are not moved
+		{
+			[][2]float64{{0, 0}, {10, 10}},
+			AxisHorizontal,
+			[][2]float64{{0, 0}, {10, 10}},
+		},
+	}
+	for _, test := range tests {
+		pm, ids := getTestArrangeMap(test.positions)
+		if _, err := pm.DistributeResources(ids, test.axis); err != nil {
+			t.Fatal(err)
+		}
+		if got := getTestPositions(pm, ids); !equalPositions(got, test.expected) {
+			t.Errorf("Distribute %v: expected %v, got %v", test.axis, test.expected, got)
+		}
+	}
+}
+
+func TestSnapResources(t *testing.T) {
+	var tests = []struct {
+		gridSize float64
+		position [2]float64
+		expected [2]float64
+	}{
+		{0, [2]float64{12, 13}, [2]float64{0, 25}},
+		{0, [2]float64{-12, -13}, [2]float64{0, -25}},
+		{10, [2]float64{14.9, 15}, [2]float64{10, 20}},
+		{100, [2]float64{149, -151}, [2]float64{100, -200}},
+	}
+	for _, test := range tests {
+		pm, ids := getTestArrangeMap([][2]float64{test.position})
+		pm.GridSize = test.gridSize
+		if _, err := pm.SnapResources(ids); err != nil {
+			t.Fatal(err)
+		}
+		if got := getTestPositions(pm, ids)[0]; got != test.expected {
+			t.Errorf("Snap %v to %v: expected %v, got %v", test.position, test.gridSize, test.expected, got)
+		}
+	}
+}
+
+func TestArrangePinned(t *testing.T) {
+	positions := [][2]float64{{0, 0}, {50, 50}, {100, 100}, {7, 7}}
+	var tests = []struct {
+		name    string
+		arrange func(pm *ProxyMap, ids []ResourceID) ([]ResourceID, error)
+	}{
+		{"align", func(pm *ProxyMap, ids []ResourceID) ([]ResourceID, error) {
+			return pm.AlignResources(ids, AlignRight)
+		}},
+		{"distribute", func(pm *ProxyMap, ids []ResourceID) ([]ResourceID, error) {
+			return pm.DistributeResources(ids, AxisHorizontal)
+		}},
+		{"snap", func(pm *ProxyMap, ids []ResourceID) ([]ResourceID, error) {
+			return pm.SnapResources(ids)
+		}},
+	}
+	for _, test := range tests {
+		pm, ids := getTestArrangeMap(positions)
+		pm.GetResource(ids[3]).Pinned = true
+		skipped, err := test.arrange(pm, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(skipped) != 1 || skipped[0] != ids[3] {
+			t.Errorf("%s: expected pinned resource to be skipped, got %v", test.name, skipped)
+		}
+		if got := getTestPositions(pm, ids)[3]; got != positions[3] {
+			t.Errorf("%s: expected pinned resource to stay in place, got %v", test.name, got)
+		}
+	}
+
+	pm, _ := getTestArrangeMap(positions)
+	if _, err := pm.AlignResources([]ResourceID{99}, AlignLeft); err == nil {
+		t.Error("Expected error for unknown resource")
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cssGrammar validates value of a CSS property.
+type cssGrammar func(value string) bool
+
+var (
+	cssHexColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	cssColorFnRegexp  = regexp.MustCompile(`^(rgb|rgba|hsl|hsla)\(\s*[0-9.]+(deg|%)?(\s*,\s*|\s+)[0-9.]+%?(\s*,\s*|\s+)[0-9.]+%?(\s*[,/]\s*[0-9.]+%?)?\s*\)$`)
+	cssLengthRegexp   = regexp.MustCompile(`^-?([0-9]+|[0-9]*\.[0-9]+)(px|em|rem|%|pt|vh|vw|ex|ch)?$`)
+	cssNumberRegexp   = regexp.MustCompile(`^([0-9]+|[0-9]*\.[0-9]+)$`)
+	cssFontNameRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9 -]*|"[a-zA-Z0-9 -]+"|'[a-zA-Z0-9 -]+')$`)
+
+	// cssNamedColors are CSS named colors allowed as color values
+	cssNamedColors = newStringSet("transparent currentcolor black silver gray grey white maroon red purple fuchsia green lime olive yellow navy blue teal aqua orange aliceblue antiquewhite aquamarine azure beige bisque blanchedalmond blueviolet brown burlywood cadetblue chartreuse chocolate coral cornflowerblue cornsilk crimson cyan darkblue darkcyan darkgoldenrod darkgray darkgreen darkgrey darkkhaki darkmagenta darkolivegreen darkorange darkorchid darkred darksalmon darkseagreen darkslateblue darkslategray darkslategrey darkturquoise darkviolet deeppink deepskyblue dimgray dimgrey dodgerblue firebrick floralwhite forestgreen gainsboro ghostwhite gold goldenrod greenyellow honeydew hotpink indianred indigo ivory khaki lavender lavenderblush lawngreen lemonchiffon lightblue lightcoral lightcyan lightgoldenrodyellow lightgray lightgreen lightgrey lightpink lightsalmon lightseagreen lightskyblue lightslategray lightslategrey lightsteelblue lightyellow limegreen linen magenta mediumaquamarine mediumblue mediumorchid mediumpurple mediumseagreen mediumslateblue mediumspringgreen mediumturquoise mediumvioletred midnightblue mintcream mistyrose moccasin navajowhite oldlace olivedrab orangered orchid palegoldenrod palegreen paleturquoise palevioletred papayawhip peachpuff peru pink plum powderblue rebeccapurple rosybrown royalblue saddlebrown salmon sandybrown seagreen seashell sienna skyblue slateblue slategray slategrey snow springgreen steelblue tan thistle tomato turquoise violet wheat whitesmoke yellowgreen")
+
+	cssLineStyles = "none hidden solid dashed dotted double groove ridge inset outset"
+
+	// cssProperties is allowlist of CSS properties and their value grammars
+	cssProperties = map[string]cssGrammar{
+		"color":            cssColor,
+		"background-color": cssColor,
+		"border-color":     cssColor,
+		"outline-color":    cssColor,
+		"fill":             cssColor,
+		"stroke":           cssColor,
+		"opacity":          cssUnitInterval,
+		"fill-opacity":     cssUnitInterval,
+		"stroke-opacity":   cssUnitInterval,
+		"stroke-width":     cssLength,
+		"font-size":        cssLength,
+		"line-height":      cssLength,
+		"letter-spacing":   cssLength,
+		"width":            cssLength,
+		"height":           cssLength,
+		"border-width":     cssList(cssLength, 4),
+		"border-radius":    cssList(cssLength, 4),
+		"outline-width":    cssLength,
+		"padding":          cssList(cssLength, 4),
+		"margin":           cssList(cssLength, 4),
+		"font-family":      cssFontFamily,
+		"font-weight":      cssKeywords("normal bold bolder lighter 100 200 300 400 500 600 700 800 900"),
+		"font-style":       cssKeywords("normal italic oblique"),
+		"text-decoration":  cssList(cssKeywords("none underline overline line-through"), 3),
+		"text-transform":   cssKeywords("none uppercase lowercase capitalize"),
+		"border-style":     cssList(cssKeywords(cssLineStyles), 4),
+		"outline-style":    cssKeywords(cssLineStyles),
+		"border":           cssList(cssAny(cssLength, cssKeywords(cssLineStyles), cssColor), 3),
+		"outline":          cssList(cssAny(cssLength, cssKeywords(cssLineStyles), cssColor), 3),
+		"visibility":       cssKeywords("visible hidden"),
+	}
+)
+
+// StyleRejection describes a style rule rejected by sanitizer.
+type StyleRejection struct {
+	// Where tells which style contained the rule
+	Where    string `json:"where"`
+	Property string `json:"property"`
+	Value    string `json:"value"`
+	Reason   string `json:"reason"`
+}
+
+// StyleError is returned when style rules are rejected.
+type StyleError struct {
+	Rejected []StyleRejection
+}
+
+func (e *StyleError) Error() string {
+	var msgs []string
+	for _, r := range e.Rejected {
+		msgs = append(msgs, fmt.Sprintf("%s: %s: %s", r.Where, r.Property, r.Reason))
+	}
+	return "rejected style rules: " + strings.Join(msgs, ", ")
+}
+
+// ValidateCSSRule returns error if CSS property is not allowed or its
+// value does not match the grammar of the property.
+func ValidateCSSRule(property string, value string) error {
+	grammar, ok := cssProperties[strings.ToLower(property)]
+	if !ok {
+		return fmt.Errorf("property not allowed")
+	}
+	if !grammar(strings.TrimSpace(value)) {
+		return fmt.Errorf("invalid value")
+	}
+	return nil
+}
+
+// SanitizeRules returns allowed rules and rejections for the rest.
+// where is used in rejections to tell where the rules came from.
+func SanitizeRules(where string, rules map[string]string) (map[string]string, []StyleRejection) {
+	if rules == nil {
+		return nil, nil
+	}
+
+	// sort for predictable rejection order
+	var props []string
+	for prop := range rules {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	clean := make(map[string]string)
+	var rejected []StyleRejection
+	for _, prop := range props {
+		value := rules[prop]
+		if err := ValidateCSSRule(prop, value); err != nil {
+			rejected = append(rejected, StyleRejection{
+				Where:    where,
+				Property: prop,
+				Value:    value,
+				Reason:   err.Error(),
+			})
+			continue
+		}
+		clean[strings.ToLower(prop)] = strings.TrimSpace(value)
+	}
+	return clean, rejected
+}
+
+// checkRules returns StyleError if any of the rules is rejected.
+func checkRules(where string, rules map[string]string) error {
+	if _, rejected := SanitizeRules(where, rules); len(rejected) > 0 {
+		return &StyleError{Rejected: rejected}
+	}
+	return nil
+}
+
+// styleSanitizer removes rejected rules from styles and collects
+// the rejections.
+type styleSanitizer struct {
+	rejected []StyleRejection
+}
+
+func (s *styleSanitizer) rules(where string, rules *map[string]string) {
+	clean, r := SanitizeRules(where, *rules)
+	if len(r) > 0 {
+		*rules = clean
+		s.rejected = append(s.rejected, r...)
+	}
+}
+
+func (s *styleSanitizer) styles(prefix string, styles []Style) {
+	for i := range styles {
+		s.rules(prefix+"style "+styles[i].SClass, &styles[i].Rules)
+	}
+}
+
+func (s *styleSanitizer) styleRules(prefix string, rules []StyleRule) {
+	for i := range rules {
+		s.rules(prefix+"style rule "+rules[i].Name, &rules[i].Rules)
+	}
+}
+
+func (s *styleSanitizer) zones(prefix string, zones []*Zone2D, newZone *OpenZone2D) {
+	for _, z := range zones {
+		s.rules(prefix+"zone "+z.Label, &z.Style)
+	}
+	if newZone != nil {
+		s.rules(prefix+"zone "+newZone.Label, &newZone.Style)
+	}
+}
+
+// sanitizeStyles removes rejected rules from all styles of the map.
+// Returns the rejected rules.
+func (p *ProxyMap) sanitizeStyles() []StyleRejection {
+	var s styleSanitizer
+	s.styles("", p.Styles)
+	s.styleRules("", p.StyleRules)
+	for _, rsrc := range p.Resources {
+		s.rules("resource "+strconv.Itoa(int(rsrc.ResourceID)), &rsrc.Style.Rules)
+	}
+	s.zones("", p.Zones, p.NewZone)
+	return s.rejected
+}
+
+// sanitizeTheme removes rejected rules from theme styles.
+func sanitizeTheme(t *Theme) []StyleRejection {
+	var s styleSanitizer
+	s.styles("theme "+t.ID+" ", t.Styles)
+	return s.rejected
+}
+
+// sanitizeTemplate removes rejected rules from template styles and zones.
+func sanitizeTemplate(t *Template) []StyleRejection {
+	var s styleSanitizer
+	prefix := "template " + t.ID + " "
+	s.styles(prefix, t.Styles)
+	s.styleRules(prefix, t.StyleRules)
+	s.zones(prefix, t.Zones, t.NewZone)
+	return s.rejected
+}
+
+// logRejectedStyles logs style rules rejected from given file.
+func logRejectedStyles(path string, rejected []StyleRejection) {
+	for _, r := range rejected {
+		log.WithFields(log.Fields{
+			"path":     path,
+			"where":    r.Where,
+			"property": r.Property,
+			"value":    r.Value,
+			"reason":   r.Reason,
+		}).Error("Rejected style rule")
+	}
+}
+
+func cssColor(value string) bool {
+	v := strings.ToLower(value)
+	return cssNamedColors[v] || cssHexColorRegexp.MatchString(v) || cssColorFnRegexp.MatchString(v)
+}
+
+func cssLength(value string) bool {
+	return cssLengthRegexp.MatchString(strings.ToLower(value))
+}
+
+func cssUnitInterval(value string) bool {
+	if !cssNumberRegexp.MatchString(value) {
+		return false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	return err == nil && f >= 0 && f <= 1
+}
+
+func cssFontFamily(value string) bool {
+	for _, name := range strings.Split(value, ",") {
+		if !cssFontNameRegexp.MatchString(strings.TrimSpace(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// cssKeywords returns grammar accepting one of space separated keywords.
+func cssKeywords(keywords string) cssGrammar {
+	set := newStringSet(keywords)
+	return func(value string) bool {
+		return set[strings.ToLower(value)]
+	}
+}
+
+// cssAny returns grammar accepting value valid in any of given grammars.
+func cssAny(grammars ...cssGrammar) cssGrammar {
+	return func(value string) bool {
+		for _, g := range grammars {
+			if g(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// cssList returns grammar accepting 1 to max space separated values
+// of given grammar.
+func cssList(grammar cssGrammar, max int) cssGrammar {
+	return func(value string) bool {
+		parts := strings.Fields(value)
+		if len(parts) == 0 || len(parts) > max {
+			return false
+		}
+		for _, part := range parts {
+			if !grammar(part) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// newStringSet creates set from space separated words.
+func newStringSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestValidateCSSRule(t *testing.T) {
+	valid := map[string][]string{
+		"color":       {"#375eab", "#fff", "red", "rgb(255, 0, 0)", "rgba(0,0,0,0.5)", "hsl(120deg 50% 50% / 0.5)"},
+		"opacity":     {"0", "0.5", "1"},
+		"font-size":   {"12px", "1.5em", "0"},
+		"margin":      {"1px 2px 3px 4px"},
+		"font-family": {"Arial, \"DejaVu Sans\", sans-serif"},
+		"border":      {"1px solid #000"},
+	}
+	for prop, values := range valid {
+		for _, v := range values {
+			if err := ValidateCSSRule(prop, v); err != nil {
+				t.Errorf("Expected %s: %s to be valid, got %s", prop, v, err)
+			}
+		}
+	}
+
+	invalid := map[string][]string{
+		"color":            {"url(http://evil/x)", "expression(alert(1))", "red; background: url(x)", "javascript:alert(1)"},
+		"background-image": {"url(http://evil/x)"},
+		"behavior":         {"url(x.htc)"},
+		"opacity":          {"2", "-1"},
+		"font-family":      {"x\\, url(y)"},
+		"margin":           {"1px 2px 3px 4px 5px"},
+	}
+	for prop, values := range invalid {
+		for _, v := range values {
+			if err := ValidateCSSRule(prop, v); err == nil {
+				t.Errorf("Expected %s: %s to be rejected", prop, v)
+			}
+		}
+	}
+}
+
+func TestSanitizeStyles(t *testing.T) {
+	pm := getTestProxyMap()
+	pm.Styles = []Style{{
+		SClass: "go",
+		Rules: map[string]string{
+			"color":            "#375eab",
+			"background-image": "url(http://evil/x)",
+		},
+	}}
+	rejected := pm.sanitizeStyles()
+	if len(rejected) != 1 || rejected[0].Property != "background-image" {
+		t.Error("Expected background-image to be rejected, got", rejected)
+	}
+	if pm.Styles[0].Rules["color"] != "#375eab" {
+		t.Error("Expected allowed rule to be kept")
+	}
+	if _, ok := pm.Styles[0].Rules["background-image"]; ok {
+		t.Error("Expected rejected rule to be removed")
+	}
+}
@@ -5,31 +5,49 @@
 
 package model
 
+// NewDefaultStyles returns a color style for each language in the
+// language catalogue.
 func NewDefaultStyles() []Style {
-	var styles []Style
-	styles = append(styles, Style{
-		SClass: "go",
-		Rules: map[string]string{
-			"color": "#375eab",
-		},
-	})
-	styles = append(styles, Style{
-		SClass: "html",
-		Rules: map[string]string{
-			"color": "#ff0000",
-		},
-	})
-	styles = append(styles, Style{
-		SClass: "md",
-		Rules: map[string]string{
-			"color": "#00ff00",
-		},
-	})
-	styles = append(styles, Style{
-		SClass: "ts",
-		Rules: map[string]string{
-			"color": "#0000ff",
-		},
+	return newLanguageStyles(func(l *Language) string {
+		return l.Color
 	})
+}
+
+// newLanguageStyles creates a color style for each language in the
+// language catalogue. Color for a language is given by function color.
+func newLanguageStyles(color func(l *Language) string) []Style {
+	var styles []Style
+	for i := range languages {
+		l := &languages[i]
+		styles = append(styles, Style{
+			SClass: l.SClass,
+			Rules: map[string]string{
+				"color": color(l),
+			},
+		})
+	}
 	return styles
 }
+
+// legacyDefaultStyles are colors by style class of default styles
+// which were written to maps before themes.
+var legacyDefaultStyles = map[string]string{
+	"go":   "#375eab",
+	"html": "#ff0000",
+	"md":   "#00ff00",
+	"ts":   "#0000ff",
+}
+
+// dropLegacyStyles removes styles equal to legacy default styles from
+// the map, so styles of the map theme are used instead.
+func (p *ProxyMap) dropLegacyStyles() {
+	styles := make([]Style, 0, len(p.Styles))
+	for _, s := range p.Styles {
+		color, ok := legacyDefaultStyles[s.SClass]
+		if ok && len(s.Rules) == 1 && s.Rules["color"] == color {
+			continue
+		}
+		styles = append(styles, s)
+	}
+	p.Styles = styles
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/fuzzy"
+	"github.com/filemaps/filemaps/pkg/scanner"
+	"github.com/filemaps/filemaps/pkg/watcher"
+)
+
+const (
+	// DefaultFindLimit is number of matches returned by default.
+	DefaultFindLimit = 50
+	// findIndexExpiry is how long unused find index is kept.
+	findIndexExpiry = 10 * time.Minute
+	// findIndexMaxAge is how long find index without a watcher
+	// is used before it is rebuilt.
+	findIndexMaxAge = 30 * time.Second
+)
+
+var (
+	findIndexesMu sync.Mutex
+	findIndexes   = make(map[int]*findIndex) // map ID -> index
+)
+
+// FindMatch is a file matching a find query.
+type FindMatch struct {
+	// Path is relative to map base
+	Path string `json:"path"`
+	fuzzy.Match
+	// InMap tells if file is a resource of the map
+	InMap      bool       `json:"inMap"`
+	ResourceID ResourceID `json:"resourceId"`
+}
+
+// FindResult contains best matches of a find query.
+type FindResult struct {
+	Matches []FindMatch `json:"matches"`
+	// Total is number of all matching files
+	Total int `json:"total"`
+	// Indexed is number of files in the index
+	Indexed int `json:"indexed"`
+}
+
+// findIndex contains paths of files under map base. It is kept up to
+// date by a native watcher, and closes itself when not used.
+type findIndex struct {
+	// key identifies base and scan options the index was built with
+	key   string
+	built time.Time
+	w     watcher.Watcher
+	// match filters created files like the scan building the index
+	match *scanner.Matcher
+	stop  chan struct{}
+	once  sync.Once
+
+	mu    sync.RWMutex
+	paths map[string]bool
+	used  time.Time
+	// live is false when watcher is not available
+	live bool
+	// stale is true when ignore files have changed
+	stale bool
+}
+
+// Find returns files under map base matching fuzzy query, best first.
+// Files are found with scan options of the map. Index of files is
+// built on first query and updated when files are created or removed.
+func (p *ProxyMap) Find(ctx context.Context, query string, limit int) (*FindResult, error) {
+	p.Read()
+	idx, err := p.getFindIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paths := idx.list()
+
+	result := &FindResult{
+		Matches: make([]FindMatch, 0),
+		Indexed: len(paths),
+	}
+	for _, path := range paths {
+		if m, ok := fuzzy.Find(query, filepath.ToSlash(path)); ok {
+			result.Matches = append(result.Matches, FindMatch{Path: path, Match: m})
+		}
+	}
+	result.Total = len(result.Matches)
+	sort.Slice(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) < len(b.Path)
+		}
+		return a.Path < b.Path
+	})
+	if limit > 0 && len(result.Matches) > limit {
+		result.Matches = result.Matches[:limit]
+	}
+
+	byPath := make(map[string]*Resource)
+	for _, rsrc := range p.Resources {
+		byPath[rsrc.Path] = rsrc
+	}
+	for i := range result.Matches {
+		if rsrc := byPath[result.Matches[i].Path]; rsrc != nil {
+			result.Matches[i].InMap = true
+			result.Matches[i].ResourceID = rsrc.ResourceID
+		}
+	}
+	return result, nil
+}
+
+// getFindIndex returns up to date index of the map, building it
+// if needed.
+func (p *ProxyMap) getFindIndex(ctx context.Context) (*findIndex, error) {
+	opts := p.GetScanOptions()
+	b, _ := json.Marshal(opts)
+	key := string(b)
+
+	findIndexesMu.Lock()
+	idx := findIndexes[p.ID]
+	findIndexesMu.Unlock()
+	if idx != nil && idx.key == key && idx.valid() {
+		return idx, nil
+	}
+	p.closeFindIndex()
+
+	idx, err := p.buildFindIndex(ctx, opts, key)
+	if err != nil {
+		return nil, err
+	}
+	findIndexesMu.Lock()
+	findIndexes[p.ID] = idx
+	findIndexesMu.Unlock()
+	go idx.run(p.ID)
+	return idx, nil
+}
+
+// closeFindIndex closes index of the map if there is one.
+func (p *ProxyMap) closeFindIndex() {
+	findIndexesMu.Lock()
+	idx := findIndexes[p.ID]
+	delete(findIndexes, p.ID)
+	findIndexesMu.Unlock()
+	if idx != nil {
+		idx.close()
+	}
+}
+
+func (p *ProxyMap) buildFindIndex(ctx context.Context, opts ScanOptions, key string) (*findIndex, error) {
+	walker := p.walk(ctx, opts)
+	plan := collectScan(walker, p.Base, nil)
+	if err := walker.Err(); err != nil {
+		return nil, err
+	}
+
+	idx := &findIndex{
+		key:   key,
+		built: time.Now(),
+		stop:  make(chan struct{}),
+		paths: make(map[string]bool),
+		used:  time.Now(),
+	}
+	for _, path := range plan.Paths {
+		idx.paths[path] = true
+	}
+
+	// Without native watching the index is rebuilt when queried
+	// after findIndexMaxAge, instead of polling the whole base.
+	// Files listed by git change without file events, so indexes of
+	// git sources are rebuilt too.
+	if opts.Source != scanner.SourceGit {
+		w, err := watcher.NewNative(p.Base, watchSkip(opts.Exclude))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":  p.ID,
+				"err": err,
+			}).Info("Could not watch files for find index")
+		} else {
+			idx.w = w
+			idx.match = p.newScanMatcher(opts)
+			idx.live = true
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"id":    p.ID,
+		"files": len(idx.paths),
+	}).Info("Find index built")
+	return idx, nil
+}
+
+// valid tells if index can be used, and marks it used.
+func (idx *findIndex) valid() bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.stale || (!idx.live && time.Since(idx.built) > findIndexMaxAge) {
+		return false
+	}
+	idx.used = time.Now()
+	return true
+}
+
+// list returns indexed paths.
+func (idx *findIndex) list() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	paths := make([]string, 0, len(idx.paths))
+	for path := range idx.paths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// run applies file changes to the index until it is closed or expires.
+func (idx *findIndex) run(mapID int) {
+	var events <-chan watcher.Event
+	if idx.w != nil {
+		events = idx.w.Events()
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				idx.mu.Lock()
+				idx.live = false
+				idx.mu.Unlock()
+				continue
+			}
+			idx.apply(e)
+		case <-ticker.C:
+			idx.mu.RLock()
+			idle := time.Since(idx.used)
+			idx.mu.RUnlock()
+			if idle > findIndexExpiry {
+				findIndexesMu.Lock()
+				if findIndexes[mapID] == idx {
+					delete(findIndexes, mapID)
+				}
+				findIndexesMu.Unlock()
+				idx.close()
+				return
+			}
+		case <-idx.stop:
+			return
+		}
+	}
+}
+
+// apply adds created file or removes removed file or directory.
+// Created files a scan would not find are not added. Changes of
+// ignore files mark the index stale, to be rebuilt when queried.
+func (idx *findIndex) apply(e watcher.Event) {
+	if scanner.IsIgnoreFile(filepath.ToSlash(e.Path)) {
+		idx.mu.Lock()
+		idx.stale = true
+		idx.mu.Unlock()
+		return
+	}
+	if e.Op == watcher.Create && idx.match.Exclusion(e.Path) != nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	switch e.Op {
+	case watcher.Create:
+		idx.paths[e.Path] = true
+	case watcher.Remove:
+		delete(idx.paths, e.Path)
+		for path := range idx.paths {
+			if isUnderDir(path, e.Path) {
+				delete(idx.paths, path)
+			}
+		}
+	}
+}
+
+func (idx *findIndex) close() {
+	idx.once.Do(func() {
+		close(idx.stop)
+		if idx.w != nil {
+			idx.w.Close()
+		}
+	})
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	pm := getTestScanMap(t, []string{"cmd/main.go", "pkg/model/map.go", "pkg/model/mapmanager.go", "README.md"})
+	defer os.RemoveAll(pm.Base)
+	defer pm.closeFindIndex()
+	pm.AddResource(&Resource{Path: filepath.Join("pkg", "model", "map.go")})
+
+	result, err := pm.Find(context.Background(), "map", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || result.Indexed != 4 {
+		t.Fatalf("Expected 2 of 4 files, got %d of %d", result.Total, result.Indexed)
+	}
+	m := result.Matches[0]
+	if m.Path != filepath.Join("pkg", "model", "map.go") || !m.InMap || m.ResourceID != 1 {
+		t.Errorf("Unexpected first match %+v", m)
+	}
+	if result.Matches[1].InMap {
+		t.Errorf("Unexpected match in map %+v", result.Matches[1])
+	}
+
+	// index is updated when files are created
+	if !isFindIndexLive(pm) {
+		t.Skip("native file watching not available")
+	}
+	ioutil.WriteFile(filepath.Join(pm.Base, "cmd", "mapper.go"), nil, 0600)
+	deadline := time.Now().Add(5 * time.Second)
+	for result.Total != 3 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		if result, err = pm.Find(context.Background(), "map", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if result.Total != 3 || len(result.Matches) != 1 {
+		t.Errorf("Expected 1 of 3 matches after creating file, got %d of %d", len(result.Matches), result.Total)
+	}
+}
+
+func TestFindIgnored(t *testing.T) {
+	pm := getTestScanMap(t, []string{".gitignore", "main.go", "node_modules/lib/index.js"})
+	defer os.RemoveAll(pm.Base)
+	defer pm.closeFindIndex()
+	ioutil.WriteFile(filepath.Join(pm.Base, ".gitignore"), []byte("node_modules/\n*.log\n"), 0600)
+	pm.IgnoreFiles = true
+
+	result, err := pm.Find(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Indexed != 2 {
+		t.Fatalf("Expected .gitignore and main.go indexed, got %+v", result.Matches)
+	}
+	if !isFindIndexLive(pm) {
+		t.Skip("native file watching not available")
+	}
+
+	// ignored files are not added, the last file tells when
+	// events are applied
+	ioutil.WriteFile(filepath.Join(pm.Base, "node_modules", "lib", "util.js"), nil, 0600)
+	ioutil.WriteFile(filepath.Join(pm.Base, "debug.log"), nil, 0600)
+	ioutil.WriteFile(filepath.Join(pm.Base, "util.go"), nil, 0600)
+	deadline := time.Now().Add(5 * time.Second)
+	for result.Indexed == 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		if result, err = pm.Find(context.Background(), "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if result.Indexed != 3 {
+		t.Errorf("Expected 3 indexed files, got %+v", result.Matches)
+	}
+
+	// changed ignore files rebuild the index
+	ioutil.WriteFile(filepath.Join(pm.Base, "node_modules", ".gitignore"), []byte("!lib/\n"), 0600)
+	os.Remove(filepath.Join(pm.Base, ".gitignore"))
+	deadline = time.Now().Add(5 * time.Second)
+	for result.Indexed == 3 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		if result, err = pm.Find(context.Background(), "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if result.Indexed != 6 {
+		t.Errorf("Expected 6 indexed files after removing .gitignore, got %+v", result.Matches)
+	}
+}
+
+// isFindIndexLive tells if find index of the map is updated by
+// a watcher.
+func isFindIndexLive(pm *ProxyMap) bool {
+	findIndexesMu.Lock()
+	idx := findIndexes[pm.ID]
+	findIndexesMu.Unlock()
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.live
+}
@@ -0,0 +1,357 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LanguageKind defines category of Language.
+type LanguageKind int
+
+// Language kind enum
+const (
+	KindProgramming LanguageKind = iota
+	KindMarkup
+	KindStylesheet
+	KindProse
+	KindData
+	KindConfig
+	KindBuild
+	KindBinary
+)
+
+// Converts LanguageKind to string
+func (k LanguageKind) String() string {
+	switch k {
+	case KindProgramming:
+		return "programming"
+	case KindMarkup:
+		return "markup"
+	case KindStylesheet:
+		return "stylesheet"
+	case KindProse:
+		return "prose"
+	case KindData:
+		return "data"
+	case KindConfig:
+		return "config"
+	case KindBuild:
+		return "build"
+	case KindBinary:
+		return "binary"
+	default:
+		return "unknown"
+	}
+}
+
+// Language describes a file format recognized from file name.
+type Language struct {
+	// SClass is style class assigned to resources of this language
+	SClass string       `json:"sClass"`
+	Name   string       `json:"name"`
+	Color  string       `json:"color"`
+	Kind   LanguageKind `json:"kind"`
+	// Extensions are lower case and include the leading dot
+	Extensions []string `json:"extensions"`
+	// FileNames are well-known file names, matched case-sensitively
+	FileNames []string `json:"fileNames"`
+	// Patterns are glob patterns matched against lower case file name
+	Patterns []string `json:"patterns"`
+}
+
+var (
+	languageIdx struct {
+		sync.Once
+		byExt  map[string]*Language
+		byName map[string]*Language
+	}
+)
+
+// newLanguage creates Language. Extensions and names are space separated
+// lists, names containing '*' are used as patterns.
+func newLanguage(sclass string, name string, color string, kind LanguageKind, exts string, names string) Language {
+	l := Language{
+		SClass:     sclass,
+		Name:       name,
+		Color:      color,
+		Kind:       kind,
+		Extensions: strings.Fields(exts),
+	}
+	for _, n := range strings.Fields(names) {
+		if strings.Contains(n, "*") {
+			l.Patterns = append(l.Patterns, strings.ToLower(n))
+		} else {
+			l.FileNames = append(l.FileNames, n)
+		}
+	}
+	return l
+}
+
+// languages is the built-in language catalogue.
+// Colors mostly follow GitHub Linguist. Go, HTML, Markdown and
+// TypeScript keep the colors File Maps has always used.
+var languages = []Language{
+	// Programming languages
+	newLanguage("go", "Go", "#375eab", KindProgramming, ".go", ""),
+	newLanguage("gomod", "Go Module", "#00add8", KindBuild, "", "go.mod go.sum go.work go.work.sum"),
+	newLanguage("c", "C", "#555555", KindProgramming, ".c .h", ""),
+	newLanguage("cpp", "C++", "#f34b7d", KindProgramming, ".cpp .cc .cxx .c++ .hpp .hh .hxx .h++ .ipp .tpp .inl .ino", ""),
+	newLanguage("csharp", "C#", "#178600", KindProgramming, ".cs .csx", ""),
+	newLanguage("objc", "Objective-C", "#438eff", KindProgramming, ".m .mm", ""),
+	newLanguage("java", "Java", "#b07219", KindProgramming, ".java", ""),
+	newLanguage("kotlin", "Kotlin", "#a97bff", KindProgramming, ".kt .kts", ""),
+	newLanguage("scala", "Scala", "#c22d40", KindProgramming, ".scala .sc", ""),
+	newLanguage("groovy", "Groovy", "#4298b8", KindProgramming, ".groovy .gvy .gy .gsh", "Jenkinsfile"),
+	newLanguage("clojure", "Clojure", "#db5855", KindProgramming, ".clj .cljs .cljc .edn", ""),
+	newLanguage("js", "JavaScript", "#f1e05a", KindProgramming, ".js .mjs .cjs .jsx", ""),
+	newLanguage("ts", "TypeScript", "#0000ff", KindProgramming, ".ts .tsx .mts .cts .d.ts", ""),
+	newLanguage("coffee", "CoffeeScript", "#244776", KindProgramming, ".coffee .litcoffee", ""),
+	newLanguage("dart", "Dart", "#00b4ab", KindProgramming, ".dart", ""),
+	newLanguage("py", "Python", "#3572a5", KindProgramming, ".py .pyw .pyi .pyx .pxd .gyp", "SConstruct SConscript"),
+	newLanguage("rb", "Ruby", "#701516", KindProgramming, ".rb .rake .gemspec .ru .podspec .thor", "Rakefile Gemfile Guardfile Vagrantfile Podfile Fastfile Brewfile Capfile"),
+	newLanguage("php", "PHP", "#4f5d95", KindProgramming, ".php .phtml .php3 .php4 .php5 .phps", ""),
+	newLanguage("perl", "Perl", "#0298c3", KindProgramming, ".pl .pm .t .psgi", ""),
+	newLanguage("lua", "Lua", "#000080", KindProgramming, ".lua .rockspec", ""),
+	newLanguage("rust", "Rust", "#dea584", KindProgramming, ".rs", ""),
+	newLanguage("swift", "Swift", "#f05138", KindProgramming, ".swift", ""),
+	newLanguage("zig", "Zig", "#ec915c", KindProgramming, ".zig", ""),
+	newLanguage("nim", "Nim", "#ffc200", KindProgramming, ".nim .nims .nimble", ""),
+	newLanguage("crystal", "Crystal", "#000100", KindProgramming, ".cr", ""),
+	newLanguage("d", "D", "#ba595e", KindProgramming, ".d .di", ""),
+	newLanguage("haskell", "Haskell", "#5e5086", KindProgramming, ".hs .lhs .hsc", ""),
+	newLanguage("cabal", "Cabal Config", "#483465", KindBuild, ".cabal", "cabal.project"),
+	newLanguage("elm", "Elm", "#60b5cc", KindProgramming, ".elm", ""),
+	newLanguage("erlang", "Erlang", "#b83998", KindProgramming, ".erl .hrl .app.src", "rebar.config"),
+	newLanguage("elixir", "Elixir", "#6e4a7e", KindProgramming, ".ex .exs", ""),
+	newLanguage("fsharp", "F#", "#b845fc", KindProgramming, ".fs .fsi .fsx", ""),
+	newLanguage("ocaml", "OCaml", "#3be133", KindProgramming, ".ml .mli .mll .mly", ""),
+	newLanguage("vb", "Visual Basic", "#945db7", KindProgramming, ".vb .vbs .bas", ""),
+	newLanguage("r", "R", "#198ce7", KindProgramming, ".r", ".Rprofile"),
+	newLanguage("julia", "Julia", "#a270ba", KindProgramming, ".jl", ""),
+	newLanguage("fortran", "Fortran", "#4d41b1", KindProgramming, ".f .f77 .f90 .f95 .f03 .f08 .for", ""),
+	newLanguage("pascal", "Pascal", "#e3f171", KindProgramming, ".pas .dpr .lpr", ""),
+	newLanguage("ada", "Ada", "#02f88c", KindProgramming, ".adb .ads .ada", ""),
+	newLanguage("cobol", "COBOL", "#005ca5", KindProgramming, ".cob .cbl .cpy", ""),
+	newLanguage("lisp", "Common Lisp", "#3fb68b", KindProgramming, ".lisp .lsp .cl .asd", ""),
+	newLanguage("scheme", "Scheme", "#1e4aec", KindProgramming, ".scm .ss .sld", ""),
+	newLanguage("racket", "Racket", "#3c5caa", KindProgramming, ".rkt .rktl", ""),
+	newLanguage("elisp", "Emacs Lisp", "#c065db", KindProgramming, ".el", ".emacs"),
+	newLanguage("solidity", "Solidity", "#aa6746", KindProgramming, ".sol", ""),
+	newLanguage("vala", "Vala", "#a56de2", KindProgramming, ".vala .vapi", ""),
+	newLanguage("asm", "Assembly", "#6e4c13", KindProgramming, ".asm .s .nasm", ""),
+	newLanguage("wasm", "WebAssembly Text", "#04133b", KindProgramming, ".wat .wast", ""),
+	newLanguage("cuda", "CUDA", "#3a4e3a", KindProgramming, ".cu .cuh", ""),
+	newLanguage("glsl", "GLSL", "#5686a5", KindProgramming, ".glsl .vert .frag .geom .tesc .tese .comp", ""),
+	newLanguage("hlsl", "HLSL", "#aace60", KindProgramming, ".hlsl .fx .fxh", ""),
+	newLanguage("shaderlab", "ShaderLab", "#222c37", KindProgramming, ".shader", ""),
+	newLanguage("tcl", "Tcl", "#e4cc98", KindProgramming, ".tcl .tk", ""),
+	newLanguage("awk", "Awk", "#c30e9b", KindProgramming, ".awk", ""),
+	newLanguage("sed", "sed", "#64b970", KindProgramming, ".sed", ""),
+	newLanguage("powershell", "PowerShell", "#012456", KindProgramming, ".ps1 .psm1 .psd1", ""),
+	newLanguage("shell", "Shell", "#89e051", KindProgramming, ".sh .bash .zsh .ksh .csh .tcsh .command", ".bashrc .bash_profile .bash_logout .bash_aliases .zshrc .zprofile .zshenv .profile PKGBUILD gradlew"),
+	newLanguage("fish", "fish", "#4aae47", KindProgramming, ".fish", ""),
+	newLanguage("bat", "Batchfile", "#c1f12e", KindProgramming, ".bat .cmd", ""),
+	newLanguage("vim", "Vim Script", "#199f4b", KindProgramming, ".vim .vimrc", ".vimrc _vimrc .gvimrc .exrc"),
+	newLanguage("gdscript", "GDScript", "#355570", KindProgramming, ".gd", ""),
+	newLanguage("haxe", "Haxe", "#df7900", KindProgramming, ".hx .hxml", ""),
+	newLanguage("purescript", "PureScript", "#1d222d", KindProgramming, ".purs", ""),
+	newLanguage("reason", "Reason", "#ff5847", KindProgramming, ".re .rei", ""),
+	newLanguage("rescript", "ReScript", "#ed5051", KindProgramming, ".res .resi", ""),
+	newLanguage("idris", "Idris", "#b30000", KindProgramming, ".idr .lidr", ""),
+	newLanguage("agda", "Agda", "#315665", KindProgramming, ".agda", ""),
+	newLanguage("lean", "Lean", "#4b5db5", KindProgramming, ".lean", ""),
+	newLanguage("verilog", "Verilog", "#b2b7f8", KindProgramming, ".v .vh", ""),
+	newLanguage("systemverilog", "SystemVerilog", "#dae1c2", KindProgramming, ".sv .svh", ""),
+	newLanguage("vhdl", "VHDL", "#adb2cb", KindProgramming, ".vhd .vhdl", ""),
+	newLanguage("smalltalk", "Smalltalk", "#596706", KindProgramming, ".st", ""),
+	newLanguage("gleam", "Gleam", "#ffaff3", KindProgramming, ".gleam", ""),
+	newLanguage("odin", "Odin", "#60affe", KindProgramming, ".odin", ""),
+	newLanguage("mojo", "Mojo", "#ff4c1f", KindProgramming, ".mojo", ""),
+	newLanguage("hack", "Hack", "#878787", KindProgramming, ".hack .hhi", ""),
+	newLanguage("apex", "Apex", "#1797c0", KindProgramming, ".cls .trigger", ""),
+	newLanguage("abap", "ABAP", "#e8274b", KindProgramming, ".abap", ""),
+	newLanguage("matlab", "MATLAB", "#e16737", KindProgramming, ".mat .mlx", ""),
+	newLanguage("sas", "SAS", "#b34936", KindProgramming, ".sas", ""),
+	newLanguage("stata", "Stata", "#1a5f91", KindProgramming, ".do .ado", ""),
+	newLanguage("autohotkey", "AutoHotkey", "#6594b9", KindProgramming, ".ahk", ""),
+	newLanguage("applescript", "AppleScript", "#101f1f", KindProgramming, ".applescript .scpt", ""),
+	newLanguage("nix", "Nix", "#7e7eff", KindConfig, ".nix", ""),
+	newLanguage("jupyter", "Jupyter Notebook", "#da5b0b", KindProgramming, ".ipynb", ""),
+
+	// Markup and templates
+	newLanguage("html", "HTML", "#ff0000", KindMarkup, ".html .htm .xhtml .shtml", ""),
+	newLanguage("xml", "XML", "#0060ac", KindMarkup, ".xml .xsd .xsl .xslt .plist .rss .atom .xaml .wsdl .kml .gpx .xlf .csproj .vbproj .fsproj .vcxproj .props .targets .resx .nuspec .config", ""),
+	newLanguage("svg", "SVG", "#ff9900", KindMarkup, ".svg", ""),
+	newLanguage("vue", "Vue", "#41b883", KindMarkup, ".vue", ""),
+	newLanguage("svelte", "Svelte", "#ff3e00", KindMarkup, ".svelte", ""),
+	newLanguage("astro", "Astro", "#ff5a03", KindMarkup, ".astro", ""),
+	newLanguage("handlebars", "Handlebars", "#f7931e", KindMarkup, ".hbs .handlebars .mustache", ""),
+	newLanguage("jinja", "Jinja", "#a52a22", KindMarkup, ".j2 .jinja .jinja2", ""),
+	newLanguage("twig", "Twig", "#c1d026", KindMarkup, ".twig", ""),
+	newLanguage("erb", "ERB", "#701516", KindMarkup, ".erb .rhtml", ""),
+	newLanguage("haml", "Haml", "#ece2a9", KindMarkup, ".haml", ""),
+	newLanguage("pug", "Pug", "#a86454", KindMarkup, ".pug .jade", ""),
+	newLanguage("slim", "Slim", "#2b2b2b", KindMarkup, ".slim", ""),
+	newLanguage("ejs", "EJS", "#a91e50", KindMarkup, ".ejs", ""),
+	newLanguage("liquid", "Liquid", "#67b8de", KindMarkup, ".liquid", ""),
+	newLanguage("jsp", "Java Server Pages", "#2a6277", KindMarkup, ".jsp .jspf .tag", ""),
+	newLanguage("razor", "Razor", "#512be4", KindMarkup, ".cshtml .razor", ""),
+	newLanguage("gotmpl", "Go Template", "#00add8", KindMarkup, ".tmpl .gotmpl .gohtml", ""),
+
+	// Stylesheets
+	newLanguage("css", "CSS", "#563d7c", KindStylesheet, ".css", ""),
+	newLanguage("scss", "SCSS", "#c6538c", KindStylesheet, ".scss", ""),
+	newLanguage("sass", "Sass", "#a53b70", KindStylesheet, ".sass", ""),
+	newLanguage("less", "Less", "#1d365d", KindStylesheet, ".less", ""),
+	newLanguage("stylus", "Stylus", "#ff6347", KindStylesheet, ".styl", ""),
+	newLanguage("postcss", "PostCSS", "#dc3a0c", KindStylesheet, ".pcss .postcss", ""),
+
+	// Prose and documentation
+	newLanguage("md", "Markdown", "#00ff00", KindProse, ".md .markdown .mdown .mkd .mkdn .mdx", ""),
+	newLanguage("rst", "reStructuredText", "#141414", KindProse, ".rst .rest", ""),
+	newLanguage("asciidoc", "AsciiDoc", "#73a0c5", KindProse, ".adoc .asciidoc .asc", ""),
+	newLanguage("tex", "TeX", "#3d6117", KindProse, ".tex .ltx .sty .dtx .ins", ""),
+	newLanguage("bibtex", "BibTeX", "#778899", KindProse, ".bib .bibtex", ""),
+	newLanguage("org", "Org", "#77aa99", KindProse, ".org", ""),
+	newLanguage("pod", "Pod", "#0298c3", KindProse, ".pod", ""),
+	newLanguage("roff", "Roff", "#ecdebe", KindProse, ".1 .2 .3 .4 .5 .6 .7 .8 .9 .man .roff .groff .me .ms", ""),
+	newLanguage("text", "Text", "#808080", KindProse, ".txt .text", "README LICENSE LICENCE COPYING AUTHORS CONTRIBUTORS CHANGELOG CHANGES NEWS NOTICE HISTORY TODO THANKS INSTALL CODEOWNERS OWNERS PATENTS VERSION"),
+	newLanguage("pdf", "PDF", "#b30b00", KindBinary, ".pdf", ""),
+	newLanguage("document", "Office Document", "#2b579a", KindBinary, ".doc .docx .odt .rtf .xls .xlsx .ods .ppt .pptx .odp .pages .numbers", ""),
+
+	// Data formats
+	newLanguage("json", "JSON", "#292929", KindData, ".json .jsonc .json5 .jsonl .ndjson .geojson .webmanifest .har .topojson", ".babelrc .jshintrc .jscsrc .arcconfig .watchmanconfig composer.lock"),
+	newLanguage("yaml", "YAML", "#cb171e", KindData, ".yml .yaml", ".clang-format .clang-tidy .clangd .gemrc"),
+	newLanguage("toml", "TOML", "#9c4221", KindData, ".toml", "Cargo.lock Pipfile Gopkg.lock poetry.lock"),
+	newLanguage("csv", "CSV", "#237346", KindData, ".csv .tsv .psv", ""),
+	newLanguage("sql", "SQL", "#e38c00", KindData, ".sql .ddl .dml .psql .pgsql .mysql .prc .udf .viw", ""),
+	newLanguage("graphql", "GraphQL", "#e10098", KindData, ".graphql .gql .graphqls", ""),
+	newLanguage("proto", "Protocol Buffers", "#4285f4", KindData, ".proto .textproto .pbtxt", "buf.yaml buf.gen.yaml buf.work.yaml"),
+	newLanguage("thrift", "Thrift", "#d12127", KindData, ".thrift", ""),
+	newLanguage("avro", "Avro IDL", "#0040ff", KindData, ".avdl .avsc", ""),
+	newLanguage("capnp", "Cap'n Proto", "#c42727", KindData, ".capnp", ""),
+	newLanguage("flatbuffers", "FlatBuffers", "#d1641b", KindData, ".fbs", ""),
+	newLanguage("openapi", "OpenAPI", "#85ea2d", KindData, "", "openapi.yaml openapi.yml openapi.json swagger.yaml swagger.yml swagger.json"),
+	newLanguage("diff", "Diff", "#88dddd", KindData, ".diff .patch .rej", ""),
+	newLanguage("log", "Log", "#a0a0a0", KindData, ".log", ""),
+	newLanguage("lockfile", "Lock File", "#8b8b8b", KindData, ".lock", "yarn.lock Gemfile.lock mix.lock flake.lock"),
+	newLanguage("database", "Database", "#003b57", KindBinary, ".db .sqlite .sqlite3 .mdb .accdb .dbf", ""),
+
+	// Configuration
+	newLanguage("ini", "INI", "#d1dbe0", KindConfig, ".ini .cfg .conf .cnf .properties .prefs .reg", ".editorconfig .npmrc .yarnrc .pylintrc .flake8 .coveragerc .pypirc .bazelrc .curlrc .wgetrc .inputrc .my.cnf"),
+	newLanguage("dotenv", "Dotenv", "#e5d559", KindConfig, ".env", ".env .envrc .env.*"),
+	newLanguage("hcl", "HCL", "#844fba", KindConfig, ".hcl .nomad", ""),
+	newLanguage("terraform", "Terraform", "#7b42bc", KindConfig, ".tf .tfvars .tftpl .tfbackend", ".terraform.lock.hcl"),
+	newLanguage("cue", "CUE", "#5886e1", KindConfig, ".cue", ""),
+	newLanguage("dhall", "Dhall", "#dfafff", KindConfig, ".dhall", ""),
+	newLanguage("jsonnet", "Jsonnet", "#0064bd", KindConfig, ".jsonnet .libsonnet", ""),
+	newLanguage("starlark", "Starlark", "#76d275", KindBuild, ".bzl .bazel .star .sky", "BUILD WORKSPACE Tiltfile"),
+	newLanguage("git", "Git Config", "#f44d27", KindConfig, "", ".gitignore .gitattributes .gitmodules .gitconfig .gitkeep .mailmap .git-blame-ignore-revs"),
+	newLanguage("ignore", "Ignore List", "#000000", KindConfig, "", ".dockerignore .npmignore .eslintignore .prettierignore .stylelintignore .hgignore .filemapsignore .helmignore .vscodeignore .gcloudignore .slugignore .bzrignore .cvsignore"),
+	newLanguage("nginx", "Nginx", "#009639", KindConfig, ".nginx .nginxconf", "nginx.conf"),
+	newLanguage("apache", "Apache Conf", "#d12127", KindConfig, ".apacheconf .vhost", ".htaccess .htpasswd httpd.conf apache2.conf"),
+	newLanguage("ssh", "SSH Config", "#9d2a2a", KindConfig, "", "ssh_config sshd_config known_hosts authorized_keys"),
+	newLanguage("systemd", "systemd Unit", "#30d475", KindConfig, ".service .socket .timer .target .mount .path .slice", ""),
+	newLanguage("desktop", "Desktop Entry", "#a0a0a0", KindConfig, ".desktop", ""),
+	newLanguage("certificate", "Certificate", "#c0a000", KindConfig, ".pem .crt .cer .der .key .csr .p12 .pfx .jks .pub .gpg", "id_rsa id_dsa id_ecdsa id_ed25519"),
+	newLanguage("npmpackage", "npm Package", "#cb3837", KindBuild, "", "package.json package-lock.json npm-shrinkwrap.json pnpm-lock.yaml pnpm-workspace.yaml .nvmrc .node-version"),
+	newLanguage("tsconfig", "TSConfig", "#3178c6", KindConfig, "", "tsconfig.json jsconfig.json tsconfig.*.json"),
+	newLanguage("eslint", "ESLint Config", "#4b32c3", KindConfig, "", ".eslintrc .eslintrc.* eslint.config.*"),
+	newLanguage("prettier", "Prettier Config", "#f7b93e", KindConfig, "", ".prettierrc .prettierrc.* prettier.config.*"),
+	newLanguage("babel", "Babel Config", "#f5da55", KindConfig, "", "babel.config.* .babelrc.*"),
+	newLanguage("webpack", "Webpack Config", "#8dd6f9", KindBuild, "", "webpack.config.* webpack.*.js"),
+	newLanguage("vite", "Vite Config", "#646cff", KindBuild, "", "vite.config.* vitest.config.*"),
+	newLanguage("ci", "CI Config", "#2088ff", KindConfig, "", ".travis.yml .gitlab-ci.yml appveyor.yml .appveyor.yml azure-pipelines.yml .drone.yml bitbucket-pipelines.yml .cirrus.yml codecov.yml .codecov.yml"),
+	newLanguage("editor", "Editor Config", "#23a7d2", KindConfig, ".code-workspace .sublime-project .sublime-settings .iml", ""),
+
+	// Build systems
+	newLanguage("makefile", "Makefile", "#427819", KindBuild, ".mk .mak .make", "Makefile makefile GNUmakefile BSDmakefile Kbuild Makefile.* makefile.*"),
+	newLanguage("dockerfile", "Dockerfile", "#384d54", KindBuild, ".dockerfile .containerfile", "Dockerfile Containerfile Dockerfile.* Containerfile.*"),
+	newLanguage("compose", "Docker Compose", "#2496ed", KindBuild, "", "docker-compose.yml docker-compose.yaml compose.yml compose.yaml docker-compose.*.yml docker-compose.*.yaml"),
+	newLanguage("cmake", "CMake", "#da3434", KindBuild, ".cmake", "CMakeLists.txt CMakeCache.txt CMakePresets.json"),
+	newLanguage("gradle", "Gradle", "#02303a", KindBuild, ".gradle .gradle.kts", "gradle.properties gradle-wrapper.properties"),
+	newLanguage("maven", "Maven POM", "#c71a36", KindBuild, ".pom", "pom.xml"),
+	newLanguage("ant", "Ant Build", "#a9157e", KindBuild, "", "build.xml ivy.xml"),
+	newLanguage("sbt", "sbt", "#c22d40", KindBuild, ".sbt", ""),
+	newLanguage("meson", "Meson", "#007800", KindBuild, "", "meson.build meson_options.txt meson.options"),
+	newLanguage("ninja", "Ninja", "#a0a0a0", KindBuild, ".ninja", ""),
+	newLanguage("autotools", "Autotools", "#a0a0a0", KindBuild, ".m4 .ac .am", "configure configure.ac configure.in Makefile.am Makefile.in"),
+	newLanguage("qmake", "QMake", "#41cd52", KindBuild, ".pro .pri .qbs", ""),
+	newLanguage("just", "Just", "#384d54", KindBuild, ".just", "justfile Justfile .justfile"),
+	newLanguage("procfile", "Procfile", "#3b2f63", KindBuild, "", "Procfile"),
+	newLanguage("helm", "Helm Chart", "#0f1689", KindBuild, "", "Chart.yaml Chart.lock values.yaml"),
+	newLanguage("nuget", "NuGet", "#004880", KindBuild, ".sln .slnx", "nuget.config packages.config Directory.Build.props Directory.Build.targets"),
+	newLanguage("pip", "Pip Requirements", "#3572a5", KindBuild, "", "requirements.txt requirements-*.txt requirements_*.txt constraints.txt setup.py setup.cfg pyproject.toml tox.ini MANIFEST.in"),
+	newLanguage("cargo", "Cargo", "#dea584", KindBuild, "", "Cargo.toml rust-toolchain rust-toolchain.toml"),
+	newLanguage("glide", "Glide", "#375eab", KindBuild, "", "glide.yaml glide.lock Gopkg.toml"),
+
+	// Binary assets
+	newLanguage("image", "Image", "#a074c4", KindBinary, ".png .jpg .jpeg .jpe .gif .bmp .ico .icns .webp .tif .tiff .psd .xcf .avif .heic .heif .raw .cr2 .nef .exr .hdr .tga", ""),
+	newLanguage("font", "Font", "#6b6b6b", KindBinary, ".ttf .otf .woff .woff2 .eot .fon .pfb", ""),
+	newLanguage("audio", "Audio", "#ff8c00", KindBinary, ".mp3 .wav .ogg .oga .flac .aac .m4a .wma .opus .mid .midi .aiff", ""),
+	newLanguage("video", "Video", "#ff4500", KindBinary, ".mp4 .m4v .mov .avi .mkv .webm .wmv .flv .mpg .mpeg .ogv .3gp", ""),
+	newLanguage("archive", "Archive", "#8b4513", KindBinary, ".zip .tar .gz .tgz .bz2 .tbz2 .xz .txz .7z .rar .zst .lz .lzma .z .cab .iso .dmg .deb .rpm .apk .tar.gz .tar.bz2 .tar.xz .tar.zst", ""),
+	newLanguage("jar", "Java Archive", "#b07219", KindBinary, ".jar .war .ear .aar .class", ""),
+	newLanguage("binary", "Binary", "#4d4d4d", KindBinary, ".exe .dll .so .dylib .a .lib .o .obj .ko .elf .bin .dat .pyc .pyo .beam .wasm .node", ""),
+	newLanguage("model3d", "3D Model", "#1e90ff", KindBinary, ".obj3d .stl .fbx .gltf .glb .blend .dae .3ds .ply", ""),
+}
+
+// GetLanguages returns the language catalogue.
+func GetLanguages() []Language {
+	return languages
+}
+
+// LookupLanguage returns Language for given file path or nil if not known.
+// Well-known file names are checked first, then file name patterns, and
+// then extensions from the longest (".tar.gz") to the shortest (".gz").
+func LookupLanguage(path string) *Language {
+	languageIdx.Do(buildLanguageIdx)
+
+	name := filepath.Base(path)
+	if l := languageIdx.byName[name]; l != nil {
+		return l
+	}
+	name = strings.ToLower(name)
+	for i := range languages {
+		for _, pattern := range languages[i].Patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return &languages[i]
+			}
+		}
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if l := languageIdx.byExt[name[i:]]; l != nil {
+			return l
+		}
+	}
+	return nil
+}
+
+// buildLanguageIdx builds lookup tables from language catalogue.
+// If the same extension or name appears twice, the first one is used.
+func buildLanguageIdx() {
+	languageIdx.byExt = make(map[string]*Language)
+	languageIdx.byName = make(map[string]*Language)
+	for i := range languages {
+		l := &languages[i]
+		for _, ext := range l.Extensions {
+			if _, ok := languageIdx.byExt[ext]; !ok {
+				languageIdx.byExt[ext] = l
+			}
+		}
+		for _, name := range l.FileNames {
+			if _, ok := languageIdx.byName[name]; !ok {
+				languageIdx.byName[name] = l
+			}
+		}
+	}
+}
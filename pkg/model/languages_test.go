@@ -0,0 +1,52 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestLookupLanguage(t *testing.T) {
+	tests := map[string]string{
+		"main.go":                "go",
+		"go.mod":                 "gomod",
+		"Makefile":               "makefile",
+		"build/Dockerfile":       "dockerfile",
+		"build/Dockerfile.dev":   "dockerfile",
+		"api/service.proto":      "proto",
+		"infra/main.tf":          "terraform",
+		"db/schema.SQL":          "sql",
+		"web/index.d.ts":         "ts",
+		"dist/release.tar.gz":    "archive",
+		"CMakeLists.txt":         "cmake",
+		"notes.txt":              "text",
+		"docs/README":            "text",
+		"config/.eslintrc.json":  "eslint",
+		"testdata/unknown.xyzzy": "",
+	}
+	for path, sclass := range tests {
+		l := LookupLanguage(path)
+		got := ""
+		if l != nil {
+			got = l.SClass
+		}
+		if got != sclass {
+			t.Errorf("LookupLanguage(%q) = %q, expected %q", path, got, sclass)
+		}
+	}
+}
+
+func TestAssignResourceStyle(t *testing.T) {
+	pm := getTestProxyMap()
+	rsrc := &Resource{Path: "Makefile"}
+	if sclass := pm.AssignResourceStyle(rsrc); sclass != "makefile" {
+		t.Error("Expected style class makefile, got", sclass)
+	}
+	rsrc = &Resource{Path: "data.Xyzzy"}
+	if sclass := pm.AssignResourceStyle(rsrc); sclass != "xyzzy" {
+		t.Error("Expected extension as style class, got", sclass)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// BaseZ is Z coordinate of the lowest layer.
+	BaseZ = 5
+	// LayerHeight is distance between two layers on Z axis.
+	LayerHeight = 250
+)
+
+// LayerMode defines how resources are stacked on Z axis.
+type LayerMode int
+
+// Layer mode enum
+const (
+	// LayerFlat puts all resources on the same plane.
+	LayerFlat LayerMode = iota
+	// LayerDepth uses directory depth as layer.
+	LayerDepth
+	// LayerPackage puts each top-level directory on its own layer.
+	LayerPackage
+)
+
+// Converts LayerMode to string
+func (l LayerMode) String() string {
+	switch l {
+	case LayerFlat:
+		return "flat"
+	case LayerDepth:
+		return "depth"
+	case LayerPackage:
+		return "package"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLayerMode converts string to LayerMode.
+func ParseLayerMode(s string) (LayerMode, error) {
+	switch s {
+	case "flat", "":
+		return LayerFlat, nil
+	case "depth":
+		return LayerDepth, nil
+	case "package":
+		return LayerPackage, nil
+	}
+	return LayerFlat, fmt.Errorf("Unknown layer mode %q", s)
+}
+
+// Layer is a plane of resources sharing the same Z coordinate.
+type Layer struct {
+	Z           float64      `json:"z"`
+	ResourceIDs []ResourceID `json:"ids"`
+}
+
+// LayerZ returns Z coordinate for layer index.
+func LayerZ(layer int) float64 {
+	return BaseZ + float64(layer)*LayerHeight
+}
+
+// AssignLayers sets Z coordinate for given resources using map's LayerMode.
+// Pinned resources keep their Z coordinate.
+func (p *ProxyMap) AssignLayers(resources []*Resource) {
+	p.Read()
+
+	var packages map[string]int
+	if p.LayerMode == LayerPackage {
+		packages = p.packageLayers()
+	}
+
+	for _, rsrc := range resources {
+		if rsrc.Pinned {
+			continue
+		}
+		switch p.LayerMode {
+		case LayerDepth:
+			rsrc.Pos.Z = LayerZ(pathDepth(rsrc.Path))
+		case LayerPackage:
+			rsrc.Pos.Z = LayerZ(packages[topLevelDir(rsrc.Path)])
+		default:
+			rsrc.Pos.Z = LayerZ(0)
+		}
+	}
+	p.Changed = true
+}
+
+// Relayer changes LayerMode of the map and reassigns Z coordinate
+// for all unpinned resources.
+func (p *ProxyMap) Relayer(mode LayerMode) {
+	p.Read()
+	p.LayerMode = mode
+	p.AssignLayers(p.Resources)
+}
+
+// GetLayers returns resources grouped by Z coordinate, lowest layer first.
+func (p *ProxyMap) GetLayers() []Layer {
+	p.Read()
+	idx := make(map[float64]int)
+	layers := make([]Layer, 0)
+	for _, rsrc := range p.Resources {
+		i, ok := idx[rsrc.Pos.Z]
+		if !ok {
+			i = len(layers)
+			idx[rsrc.Pos.Z] = i
+			layers = append(layers, Layer{Z: rsrc.Pos.Z})
+		}
+		layers[i].ResourceIDs = append(layers[i].ResourceIDs, rsrc.ResourceID)
+	}
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].Z < layers[j].Z
+	})
+	return layers
+}
+
+// SetLayer moves given resources to Z coordinate z.
+func (p *ProxyMap) SetLayer(ids []ResourceID, z float64) error {
+	rsrcs, err := p.getResources(ids)
+	if err != nil {
+		return err
+	}
+	for _, rsrc := range rsrcs {
+		rsrc.Pos.Z = z
+	}
+	p.Changed = true
+	return nil
+}
+
+// packageLayers returns layer index for each top-level directory in map.
+// Files in map base are on layer 0, directories follow in name order.
+func (p *ProxyMap) packageLayers() map[string]int {
+	var names []string
+	seen := make(map[string]bool)
+	for _, rsrc := range p.Resources {
+		name := topLevelDir(rsrc.Path)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	layers := make(map[string]int)
+	for i, name := range names {
+		layers[name] = i + 1
+	}
+	return layers
+}
+
+// pathDepth returns number of directories in relative path.
+func pathDepth(path string) int {
+	dir := filepath.ToSlash(filepath.Dir(path))
+	if dir == "." || dir == "/" {
+		return 0
+	}
+	return strings.Count(strings.Trim(dir, "/"), "/") + 1
+}
+
+// topLevelDir returns first directory of relative path or empty string
+// if path is in map base.
+func topLevelDir(path string) string {
+	parts := strings.SplitN(strings.TrimLeft(filepath.ToSlash(path), "/"), "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
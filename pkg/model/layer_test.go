@@ -0,0 +1,91 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestParseLayerMode(t *testing.T) {
+	var tests = []struct {
+		s    string
+		mode LayerMode
+		ok   bool
+	}{
+		{"", LayerFlat, true},
+		{"flat", LayerFlat, true},
+		{"depth", LayerDepth, true},
+		{"package", LayerPackage, true},
+		{"tree", LayerFlat, false},
+	}
+	for _, test := range tests {
+		mode, err := ParseLayerMode(test.s)
+		if mode != test.mode || (err == nil) != test.ok {
+			t.Errorf("ParseLayerMode(%q) returned %v, %v", test.s, mode, err)
+		}
+		if test.ok && test.s != "" && mode.String() != test.s {
+			t.Errorf("Expected %q, got %q", test.s, mode.String())
+		}
+	}
+}
+
+func TestRelayer(t *testing.T) {
+	paths := []string{"main.go", "cmd/main.go", "pkg/model/map.go", "pkg/model/css/rules.go"}
+	var tests = []struct {
+		mode   LayerMode
+		layers []int
+	}{
+		{LayerFlat, []int{0, 0, 0, 0}},
+		{LayerDepth, []int{0, 1, 2, 3}},
+		// files in base first, then top-level dirs in name order
+		{LayerPackage, []int{0, 1, 2, 2}},
+	}
+	for _, test := range tests {
+		pm := getTestProxyMap()
+		for _, path := range paths {
+			pm.AddResource(&Resource{Path: path})
+		}
+		pinned := &Resource{Path: "pkg/pinned.go", Pinned: true}
+		pinned.Pos.Z = 42
+		pm.AddResource(pinned)
+
+		pm.Relayer(test.mode)
+		if pm.LayerMode != test.mode {
+			t.Errorf("Expected layer mode %v, got %v", test.mode, pm.LayerMode)
+		}
+		for i, layer := range test.layers {
+			rsrc := pm.Resources[i]
+			if rsrc.Pos.Z != LayerZ(layer) {
+				t.Errorf("%v: expected %s on layer %d, got Z %v", test.mode, rsrc.Path, layer, rsrc.Pos.Z)
+			}
+		}
+		if pinned.Pos.Z != 42 {
+			t.Errorf("%v: expected pinned resource to keep Z, got %v", test.mode, pinned.Pos.Z)
+		}
+	}
+}
+
+func TestSetLayer(t *testing.T) {
+	pm := getTestProxyMap()
+	a := pm.AddResource(&Resource{Path: "a.go"})
+	b := pm.AddResource(&Resource{Path: "b.go"})
+	pm.AddResource(&Resource{Path: "c.go"})
+	pm.Relayer(LayerFlat)
+
+	if err := pm.SetLayer([]ResourceID{a, b}, LayerZ(2)); err != nil {
+		t.Fatal(err)
+	}
+	layers := pm.GetLayers()
+	if len(layers) != 2 || layers[0].Z != LayerZ(0) || layers[1].Z != LayerZ(2) {
+		t.Fatalf("Unexpected layers %+v", layers)
+	}
+	if len(layers[1].ResourceIDs) != 2 {
+		t.Errorf("Expected 2 resources on layer 2, got %v", layers[1].ResourceIDs)
+	}
+	if err := pm.SetLayer([]ResourceID{a, 99}, 0); err == nil {
+		t.Error("Expected error for unknown resource")
+	}
+}
@@ -5,6 +5,10 @@
 
 package model
 
+import (
+	"github.com/filemaps/filemaps/pkg/scanner"
+)
+
 const (
 	currentMapFileDataVersion = 1
 )
@@ -19,7 +23,29 @@ type MapFileDataV1 struct {
 	Exclude     []string    `json:"exclude"`
 	Resources   []*Resource `json:"resources"`
 	Styles      []Style     `json:"styles"`
+	StyleRules  []StyleRule `json:"styleRules"`
+	Zones       []*Zone2D   `json:"zones"`
 	NewZone     *OpenZone2D `json:"newZone"`
+	LayerMode   LayerMode   `json:"layerMode"`
+	GridSize    float64     `json:"gridSize"`
+	// Placements place new resources into zones by path
+	Placements []PlacementRule `json:"placements"`
+	// IgnoreFiles enables reading .gitignore files when scanning
+	IgnoreFiles bool `json:"ignoreFiles"`
+	// FollowSymlinks enables scanning symlinked directories
+	FollowSymlinks bool `json:"followSymlinks"`
+	// Filter selects scanned files by name, size, mtime and content
+	Filter scanner.Filter `json:"filter"`
+	// ScanSource is scanner.SourceGit to scan files tracked by git
+	ScanSource string `json:"scanSource"`
+	// ScanUntracked adds untracked files not ignored by git to
+	// git scans
+	ScanUntracked bool `json:"scanUntracked"`
+	// Watch enables adding new files automatically
+	Watch bool `json:"watch"`
+	// Theme is ID of the selected Theme,
+	// Styles override styles of the theme
+	Theme string `json:"theme"`
 }
 
 // MapFileData struct
@@ -36,12 +62,17 @@ func NewMap(i MapInfo) *Map {
 	m := &Map{
 		MapInfo: i,
 		MapFileData: MapFileData{
-			Version:   currentMapFileDataVersion,
-			Title2:    i.Title,
-			Exclude:   make([]string, 0),
-			Resources: make([]*Resource, 0),
-			Styles:    NewDefaultStyles(),
-			NewZone:   NewNewZone2D(),
+			Version:    currentMapFileDataVersion,
+			Title2:     i.Title,
+			Exclude:    make([]string, 0),
+			Resources:  make([]*Resource, 0),
+			Styles:     make([]Style, 0),
+			StyleRules: make([]StyleRule, 0),
+			Zones:      make([]*Zone2D, 0),
+			Placements: make([]PlacementRule, 0),
+			NewZone:    NewNewZone2D(),
+			GridSize:   DefaultGridSize,
+			Theme:      DefaultThemeID,
 		},
 	}
 	return m
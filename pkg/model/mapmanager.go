@@ -131,6 +131,11 @@ func (mm *MapManager) findMapByFile(base string, file string) *ProxyMap {
 
 // DeleteMap deletes Map with given ID.
 func (mm *MapManager) DeleteMap(mapID int) bool {
+	if pm := mm.proxyMaps[mapID]; pm != nil {
+		pm.stopWatch()
+		pm.closeFindIndex()
+		pm.deleteSearchIndex()
+	}
 	delete(mm.proxyMaps, mapID)
 
 	for i, mi := range mm.MapInfos {
@@ -161,6 +166,7 @@ func (mm *MapManager) UpdateMap(
 	pm.File = file
 	pm.Exclude = exclude
 	pm.Changed = true
+	pm.restartWatch()
 
 	// update mm.MapInfos
 	for i, mi := range mm.MapInfos {
@@ -0,0 +1,158 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PlacementRule places new resources matching path glob into a zone.
+// Path supports ** like StyleMatch.Path.
+type PlacementRule struct {
+	Path   string `json:"path"`
+	ZoneID int    `json:"zoneId"`
+}
+
+// validatePlacements returns error if a rule refers to unknown zone.
+func validatePlacements(rules []PlacementRule, zones []*Zone2D) error {
+	for _, rule := range rules {
+		if rule.Path == "" {
+			return fmt.Errorf("placement rule has no path")
+		}
+		if findZone(zones, rule.ZoneID) == nil {
+			return fmt.Errorf("placement rule %q refers to unknown zone %d", rule.Path, rule.ZoneID)
+		}
+	}
+	return nil
+}
+
+// placementZone returns zone of the most specific placement rule
+// matching path, or nil if no rule matches. Rule with the longest
+// literal prefix is the most specific, so "cmd/app/**" wins "cmd/**".
+// The first rule wins ties.
+func (p *ProxyMap) placementZone(path string) *Zone2D {
+	path = filepath.ToSlash(path)
+	var best *PlacementRule
+	for i, rule := range p.Placements {
+		if !matchPathGlob(rule.Path, path) {
+			continue
+		}
+		if best == nil || literalPrefixLen(rule.Path) > literalPrefixLen(best.Path) {
+			best = &p.Placements[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return findZone(p.Zones, best.ZoneID)
+}
+
+// literalPrefixLen returns length of glob pattern before the first
+// wildcard.
+func literalPrefixLen(pattern string) int {
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		return i
+	}
+	return len(pattern)
+}
+
+// placeInZone places resource to the first free grid cell inside zone.
+// Returns false if zone has no free cells.
+func (p *ProxyMap) placeInZone(rsrc *Resource, z *Zone2D, occupied map[[2]int]bool) bool {
+	minX, minY, maxX, maxY, ok := z.bounds()
+	if !ok {
+		return false
+	}
+	for y := maxY; y > minY; y -= gridCellHeight {
+		for x := minX; x < maxX; x += gridCellWidth {
+			cell := p.gridCell(x, y)
+			if occupied[cell] {
+				continue
+			}
+			occupied[cell] = true
+			rsrc.Pos.X = x
+			rsrc.Pos.Y = y
+			return true
+		}
+	}
+	return false
+}
+
+// derivePlacements creates placement rules from current layout.
+// A directory gets a rule when all of its resources lie in the same zone.
+// Rules of subdirectories are left out when parent has the same zone,
+// others override the parent rule by being more specific.
+func (p *ProxyMap) derivePlacements() []PlacementRule {
+	zones := make(map[string]int)
+	for _, rsrc := range p.Resources {
+		dir := filepath.ToSlash(filepath.Dir(rsrc.Path))
+		if dir == "." {
+			continue
+		}
+		zoneID := 0
+		for _, z := range p.Zones {
+			if z.contains(rsrc.Pos) {
+				zoneID = z.ZoneID
+				break
+			}
+		}
+		if prev, ok := zones[dir]; ok && prev != zoneID {
+			// resources of the directory are in different zones
+			zoneID = 0
+		}
+		zones[dir] = zoneID
+	}
+
+	var dirs []string
+	for dir := range zones {
+		dirs = append(dirs, dir)
+	}
+	// parents are sorted before their subdirectories
+	sort.Strings(dirs)
+
+	var rules []PlacementRule
+	ruleOf := make(map[string]int)
+	for _, dir := range dirs {
+		zoneID := zones[dir]
+		if zoneID == 0 || ancestorZone(ruleOf, dir) == zoneID {
+			continue
+		}
+		ruleOf[dir] = zoneID
+		rules = append(rules, PlacementRule{
+			Path:   dir + "/**",
+			ZoneID: zoneID,
+		})
+	}
+	return rules
+}
+
+// ancestorZone returns zone of the nearest ancestor directory having
+// a placement rule, or 0 if there is none.
+func ancestorZone(ruleOf map[string]int, dir string) int {
+	for {
+		i := strings.LastIndex(dir, "/")
+		if i < 0 {
+			return 0
+		}
+		dir = dir[:i]
+		if zoneID, ok := ruleOf[dir]; ok {
+			return zoneID
+		}
+	}
+}
+
+// findZone returns zone by ID or nil if not found.
+func findZone(zones []*Zone2D, id int) *Zone2D {
+	for _, z := range zones {
+		if z.ZoneID == id {
+			return z
+		}
+	}
+	return nil
+}
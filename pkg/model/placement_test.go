@@ -0,0 +1,117 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestAssignPositionsPlacement(t *testing.T) {
+	pm := getTestProxyMap()
+	pm.Zones = []*Zone2D{newRectZone(2, "cmd", -1000, 1000, 500, 500)}
+	pm.Placements = []PlacementRule{{Path: "cmd/**", ZoneID: 2}}
+
+	cmd := &Resource{Path: "cmd/app/main.go"}
+	pkg := &Resource{Path: "pkg/app.go"}
+	pm.AddResource(cmd)
+	pm.AddResource(pkg)
+	pm.AssignPositions([]*Resource{cmd, pkg})
+
+	if !pm.Zones[0].contains(cmd.Pos) {
+		t.Error("Expected resource to be placed in zone, got", cmd.Pos)
+	}
+	if pm.Zones[0].contains(pkg.Pos) {
+		t.Error("Expected resource not to be placed in zone, got", pkg.Pos)
+	}
+}
+
+func TestDerivePlacements(t *testing.T) {
+	pm := getTestProxyMap()
+	pm.Zones = []*Zone2D{
+		newRectZone(2, "cmd", -1000, 1000, 500, 500),
+		newRectZone(3, "pkg", 0, 1000, 500, 500),
+	}
+	for _, r := range []struct {
+		path string
+		x    float64
+	}{
+		{"cmd/main.go", -900},
+		{"cmd/app/app.go", -800},
+		{"pkg/a.go", 100},
+		{"mixed/a.go", 100},
+		{"mixed/b.go", -900},
+	} {
+		rsrc := &Resource{Path: r.path}
+		rsrc.Pos = Position{X: r.x, Y: 900}
+		pm.AddResource(rsrc)
+	}
+
+	rules := pm.derivePlacements()
+	if len(rules) != 2 {
+		t.Fatal("Expected 2 placement rules, got", rules)
+	}
+	if rules[0].Path != "cmd/**" || rules[0].ZoneID != 2 {
+		t.Error("Expected cmd/** rule for zone 2, got", rules[0])
+	}
+	if rules[1].Path != "pkg/**" || rules[1].ZoneID != 3 {
+		t.Error("Expected pkg/** rule for zone 3, got", rules[1])
+	}
+}
+
+func TestPlacementNested(t *testing.T) {
+	pm := getTestProxyMap()
+	pm.Zones = []*Zone2D{
+		newRectZone(2, "cmd", -1000, 1000, 500, 500),
+		newRectZone(3, "app", 0, 1000, 500, 500),
+	}
+	var tests = []struct {
+		placements []PlacementRule
+		path       string
+		zoneID     int
+	}{
+		{[]PlacementRule{{"cmd/**", 2}, {"cmd/app/**", 3}}, "cmd/app/x.go", 3},
+		{[]PlacementRule{{"cmd/app/**", 3}, {"cmd/**", 2}}, "cmd/app/x.go", 3},
+		{[]PlacementRule{{"cmd/**", 2}, {"cmd/app/**", 3}}, "cmd/main.go", 2},
+		{[]PlacementRule{{"cmd/**", 2}, {"cmd/app/**", 3}}, "cmd/application/x.go", 2},
+		{[]PlacementRule{{"**/*.go", 3}, {"cmd/**", 2}}, "cmd/main.go", 2},
+		{[]PlacementRule{{"cmd/**", 2}, {"cmd/**", 3}}, "cmd/main.go", 2},
+		{[]PlacementRule{{"cmd/**", 2}}, "pkg/main.go", 0},
+	}
+	for _, test := range tests {
+		pm.Placements = test.placements
+		zoneID := 0
+		if z := pm.placementZone(test.path); z != nil {
+			zoneID = z.ZoneID
+		}
+		if zoneID != test.zoneID {
+			t.Errorf("Expected %s in zone %d with %v, got %d", test.path, test.zoneID, test.placements, zoneID)
+		}
+	}
+
+	// derived rules of nested directories place new files
+	pm.Placements = nil
+	for _, r := range []struct {
+		path string
+		x    float64
+	}{
+		{"cmd/main.go", -900},
+		{"cmd/app/app.go", 100},
+	} {
+		rsrc := &Resource{Path: r.path}
+		rsrc.Pos = Position{X: r.x, Y: 900}
+		pm.AddResource(rsrc)
+	}
+	pm.Placements = pm.derivePlacements()
+	if len(pm.Placements) != 2 {
+		t.Fatal("Expected 2 placement rules, got", pm.Placements)
+	}
+	rsrc := &Resource{Path: "cmd/app/x.go"}
+	pm.AddResource(rsrc)
+	pm.AssignPositions([]*Resource{rsrc})
+	if !pm.Zones[1].contains(rsrc.Pos) {
+		t.Error("Expected resource to be placed in nested directory zone, got", rsrc.Pos)
+	}
+}
@@ -11,11 +11,25 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/filemaps/filemaps/pkg/config"
 	"github.com/filemaps/filemaps/pkg/fileapp"
+	"github.com/filemaps/filemaps/pkg/preview"
+)
+
+const (
+	// gridCellWidth is horizontal distance between automatically
+	// positioned resources.
+	gridCellWidth = 200
+	// gridCellHeight is vertical distance between automatically
+	// positioned resources.
+	gridCellHeight = 125
 )
 
 // ProxyMap is virtual proxy for Map struct
@@ -23,8 +37,13 @@ type ProxyMap struct {
 	*Map
 	IsRead  bool
 	Changed bool
+	// Rejected contains style rules removed by CSS sanitizer
+	// when the map was read
+	Rejected []StyleRejection
 	// resourceIdx is resource index for internal usage
 	resourceIdx map[ResourceID]int // ResourceID -> pos in Resources array
+	// mu serializes access from HTTP requests and background jobs
+	mu sync.Mutex
 }
 
 // NewProxyMap creates a new ProxyMap
@@ -37,9 +56,23 @@ func NewProxyMap(i MapInfo) *ProxyMap {
 	return p
 }
 
+// Lock locks the map. Requests to the map hold the lock,
+// background jobs must take it before modifying the map.
+func (p *ProxyMap) Lock() {
+	p.mu.Lock()
+}
+
+// Unlock unlocks the map.
+func (p *ProxyMap) Unlock() {
+	p.mu.Unlock()
+}
+
 // Write encodes Map.MapFileData to JSON file.
+// Style rules not allowed by CSS sanitizer are not written.
 func (p *ProxyMap) Write() error {
-	return p.writeFile(p.getFilePath())
+	path := p.getFilePath()
+	logRejectedStyles(path, p.sanitizeStyles())
+	return p.writeFile(path)
 }
 
 func (p *ProxyMap) writeFile(path string) error {
@@ -108,17 +141,44 @@ func (p *ProxyMap) ParseJSON(r io.Reader) error {
 
 	p.Map.MapFileData = *data
 	p.refreshResourceIdx()
+	p.dropLegacyStyles()
+	p.Rejected = p.sanitizeStyles()
+	logRejectedStyles(p.getFilePath(), p.Rejected)
 	return nil
 }
 
-func (p *ProxyMap) OpenResource(r *Resource) {
+// OpenResource opens resource with the configured application.
+// Returns config.ErrPathNotAllowed for files outside allowed roots.
+func (p *ProxyMap) OpenResource(r *Resource) error {
 	path := filepath.Join(p.Base, r.Path)
+	if err := config.CheckPath(path); err != nil {
+		return err
+	}
 	fileapp.Open(path)
+	return nil
 }
 
-// GetResource returns Resource by ResourceID.
+// PreviewResource returns preview of resource file. Lexer is chosen
+// by language of the file.
+// Returns config.ErrPathNotAllowed for files outside allowed roots.
+func (p *ProxyMap) PreviewResource(r *Resource, opts preview.Options) (*preview.Preview, error) {
+	path := filepath.Join(p.Base, r.Path)
+	if err := config.CheckPath(path); err != nil {
+		return nil, err
+	}
+	if l := LookupLanguage(path); l != nil {
+		opts.Language = l.SClass
+	}
+	return preview.Generate(path, opts)
+}
+
+// GetResource returns Resource by ResourceID or nil if not found.
 func (p *ProxyMap) GetResource(id ResourceID) *Resource {
-	return p.Resources[p.resourceIdx[id]]
+	i, ok := p.resourceIdx[id]
+	if !ok {
+		return nil
+	}
+	return p.Resources[i]
 }
 
 // GetResourceByPath returns Resource having given path or nil.
@@ -144,12 +204,17 @@ func (p *ProxyMap) AddResource(r *Resource) ResourceID {
 }
 
 // AssignResourceStyle assigns style for give resource.
-// By default style class is determined from file name extension.
+// Style class is determined from language catalogue by file name.
+// Unknown files get file name extension as style class.
 // Returns assigned style class.
 func (p *ProxyMap) AssignResourceStyle(r *Resource) string {
 	p.Read()
-	ext := filepath.Ext(r.Path)
-	r.Style.SClass = strings.Trim(ext, ".")
+	if l := LookupLanguage(r.Path); l != nil {
+		r.Style.SClass = l.SClass
+	} else {
+		ext := filepath.Ext(r.Path)
+		r.Style.SClass = strings.ToLower(strings.Trim(ext, "."))
+	}
 	p.Changed = true
 	return r.Style.SClass
 }
@@ -166,25 +231,86 @@ func (p *ProxyMap) DeleteResource(resourceID ResourceID) {
 	p.Changed = true
 }
 
+// AssignPositions places given resources on a grid starting from NewZone.
+// Resources matching a placement rule are placed into the zone of the rule
+// while it has room.
+// Pinned resources are not moved. Pinned resources and resources not
+// given as argument are treated as obstacles.
 func (p *ProxyMap) AssignPositions(resources []*Resource) {
 	p.Read()
 
 	x := p.NewZone.Pos.X
 	y := p.NewZone.Pos.Y
 	path := ""
+	occupied := p.occupiedCells(resources)
 
 	for _, rsrc := range resources {
+		if rsrc.Pinned {
+			continue
+		}
+		if z := p.placementZone(rsrc.Path); z != nil && p.placeInZone(rsrc, z, occupied) {
+			continue
+		}
+		for occupied[p.gridCell(x, y)] {
+			x += gridCellWidth
+		}
+		occupied[p.gridCell(x, y)] = true
 		rsrc.Pos.X = x
 		rsrc.Pos.Y = y
 		rsrcPath := filepath.Dir(rsrc.Path)
 		if rsrcPath != path {
 			path = rsrcPath
 			x = p.NewZone.Pos.X
-			y -= 125
+			y -= gridCellHeight
 		} else {
-			x += 200
+			x += gridCellWidth
+		}
+	}
+}
+
+// Relayout reassigns positions and layers for all unpinned resources.
+// Resources are ordered by path so files in the same directory stay
+// together.
+func (p *ProxyMap) Relayout() {
+	p.Read()
+	var resources []*Resource
+	for _, rsrc := range p.Resources {
+		if !rsrc.Pinned {
+			resources = append(resources, rsrc)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Path < resources[j].Path
+	})
+	p.AssignPositions(resources)
+	p.AssignLayers(resources)
+	p.Changed = true
+}
+
+// occupiedCells returns grid cells taken by resources which are
+// not going to be moved.
+func (p *ProxyMap) occupiedCells(moving []*Resource) map[[2]int]bool {
+	isMoving := make(map[*Resource]bool)
+	for _, rsrc := range moving {
+		if !rsrc.Pinned {
+			isMoving[rsrc] = true
+		}
+	}
+	cells := make(map[[2]int]bool)
+	for _, rsrc := range p.Resources {
+		if !isMoving[rsrc] {
+			cells[p.gridCell(rsrc.Pos.X, rsrc.Pos.Y)] = true
 		}
 	}
+	return cells
+}
+
+// gridCell returns the nearest grid cell for given position.
+func (p *ProxyMap) gridCell(x float64, y float64) [2]int {
+	return [2]int{
+		int(math.Floor((x-p.NewZone.Pos.X)/gridCellWidth + 0.5)),
+		int(math.Floor((y-p.NewZone.Pos.Y)/gridCellHeight + 0.5)),
+	}
 }
 
 // refreshResourceIdx refreshes resource index in var resourceIdx.
@@ -219,7 +345,11 @@ func getJSONVersion(bs []byte) (float64, error) {
 	if err := json.Unmarshal(bs, &data); err != nil {
 		return -1, err
 	}
-	return data["version"].(float64), nil
+	version, ok := data["version"].(float64)
+	if !ok {
+		return -1, fmt.Errorf("JSON data has no version")
+	}
+	return version, nil
 }
 
 // Versioning
@@ -0,0 +1,43 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestAssignPositionsPinned(t *testing.T) {
+	pm := getTestProxyMap()
+	pinned := &Resource{Path: "a/pinned.go", Pinned: true}
+	pinned.Pos = Position{X: 0, Y: -125, Z: 100}
+	pm.AddResource(pinned)
+
+	var rsrcs []*Resource
+	for _, path := range []string{"a/1.go", "a/2.go", "b/1.go"} {
+		rsrc := &Resource{Path: path}
+		pm.AddResource(rsrc)
+		rsrcs = append(rsrcs, rsrc)
+	}
+	rsrcs = append(rsrcs, pinned)
+	pm.AssignPositions(rsrcs)
+	pm.AssignLayers(rsrcs)
+
+	if pinned.Pos.X != 0 || pinned.Pos.Y != -125 || pinned.Pos.Z != 100 {
+		t.Error("Expected pinned resource to stay in place, got", pinned.Pos)
+	}
+	for _, rsrc := range rsrcs[:3] {
+		if rsrc.Pos.X == pinned.Pos.X && rsrc.Pos.Y == pinned.Pos.Y {
+			t.Error("Expected resource not to overlap pinned resource", rsrc.Path)
+		}
+	}
+}
+
+func getTestProxyMap() *ProxyMap {
+	pm := NewProxyMap(MapInfo{Base: "testdata", File: "test.filemap"})
+	pm.IsRead = true
+	pm.refreshResourceIdx()
+	return pm
+}
@@ -36,7 +36,28 @@ type ResourceV1 struct {
 	Path       string       `json:"path"`
 	Pos        Position     `json:"pos"`
 	Style      Style        `json:"style"`
+	// Pinned resources are not moved by automatic layouts
+	Pinned bool     `json:"pinned"`
+	Tags   []string `json:"tags"`
+	// Target is real path of a symlinked file
+	Target string `json:"target"`
+	// Archived resources were removed or excluded from the file system
+	// but are kept in the map
+	Archived bool `json:"archived"`
+	// Missing resources were removed from the file system
+	// while the map was watched
+	Missing bool `json:"missing"`
 }
 
 // Resource is alias to the latest Resource version
 type Resource ResourceV1
+
+// HasTag returns true if resource is tagged with given tag.
+func (r *Resource) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"context"
+	log "github.com/Sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/filemaps/filemaps/pkg/scanner"
+)
+
+// ScanOptions are parameters of a scan.
+type ScanOptions struct {
+	// Path is the directory to scan
+	Path           string   `json:"path"`
+	Exclude        []string `json:"exclude"`
+	IgnoreFiles    bool     `json:"ignoreFiles"`
+	FollowSymlinks bool     `json:"followSymlinks"`
+	MaxFiles       int      `json:"maxFiles"`
+	MaxDepth       int      `json:"maxDepth"`
+	// Filter selects found files, directories are not filtered
+	Filter scanner.Filter `json:"filter"`
+	// Source is scanner.SourceGit to scan files known to git
+	Source string `json:"source"`
+	// Untracked includes untracked files in git scans
+	Untracked bool `json:"untracked"`
+	// Sync removes resources under Path which are excluded
+	// or missing
+	Sync bool `json:"sync"`
+	// Archive archives resources instead of removing them in Sync
+	Archive bool `json:"archive"`
+}
+
+// ScanPlan contains files found by a scan, before they are applied
+// to the map.
+type ScanPlan struct {
+	// Paths are found files relative to map base
+	Paths []string
+	// Targets are real paths of symlinked files by path
+	Targets   map[string]string
+	Excluded  []scanner.Exclusion
+	Truncated bool
+	// Source tells how files were listed
+	Source string
+}
+
+// ScanResult tells what a scan changed.
+type ScanResult struct {
+	// Resources are added resources
+	Resources []*Resource         `json:"-"`
+	Removed   []StaleResource     `json:"removed"`
+	Archived  []StaleResource     `json:"archived"`
+	Restored  []ResourceID        `json:"restored"`
+	Excluded  []scanner.Exclusion `json:"excluded"`
+	Truncated bool                `json:"truncated"`
+	Source    string              `json:"source"`
+}
+
+// Reasons why existing resource is not found by a scan
+const (
+	// StaleExcluded resources are excluded by a pattern
+	StaleExcluded = "excluded"
+	// StaleMissing resources do not exist anymore
+	StaleMissing = "missing"
+	// StaleSkipped resources exist but were not reached by the scan,
+	// because of MaxFiles or MaxDepth for example
+	StaleSkipped = "skipped"
+)
+
+// StaleResource is an existing resource under scanned path which
+// the scan did not find.
+type StaleResource struct {
+	ID     ResourceID `json:"id"`
+	Path   string     `json:"path"`
+	Reason string     `json:"reason"`
+	// Exclusion is set for excluded resources
+	Exclusion *scanner.Exclusion `json:"exclusion"`
+}
+
+// ScanPreview tells what a scan would change without changing anything.
+type ScanPreview struct {
+	// Added are paths of files which would be added
+	Added     []string            `json:"added"`
+	Stale     []StaleResource     `json:"stale"`
+	Excluded  []scanner.Exclusion `json:"excluded"`
+	Truncated bool                `json:"truncated"`
+	Source    string              `json:"source"`
+}
+
+// GetScanOptions returns scan options stored in the map.
+func (p *ProxyMap) GetScanOptions() ScanOptions {
+	p.Read()
+	return ScanOptions{
+		Path:           p.Base,
+		Exclude:        p.Exclude,
+		IgnoreFiles:    p.IgnoreFiles,
+		FollowSymlinks: p.FollowSymlinks,
+		Filter:         p.Filter,
+		Source:         p.ScanSource,
+		Untracked:      p.ScanUntracked,
+	}
+}
+
+// Scan scans files and adds new ones to the map.
+// Nothing is changed if ctx is cancelled.
+func (p *ProxyMap) Scan(ctx context.Context, opts ScanOptions) (*ScanResult, error) {
+	p.Read()
+	walker := p.walk(ctx, opts)
+	plan := collectScan(walker, p.Base, nil)
+	if err := walker.Err(); err != nil {
+		return nil, err
+	}
+	return p.ApplyScan(opts, plan), nil
+}
+
+// PreviewScan scans files and tells how the map would change.
+// The map is not modified.
+func (p *ProxyMap) PreviewScan(ctx context.Context, opts ScanOptions) (*ScanPreview, error) {
+	p.Read()
+	walker := p.walk(ctx, opts)
+	plan := collectScan(walker, p.Base, nil)
+	if err := walker.Err(); err != nil {
+		return nil, err
+	}
+
+	preview := &ScanPreview{
+		Added:     make([]string, 0),
+		Stale:     p.staleResources(opts, plan),
+		Excluded:  plan.Excluded,
+		Truncated: plan.Truncated,
+		Source:    plan.Source,
+	}
+	for _, path := range plan.Paths {
+		if p.GetResourceByPath(path) == nil {
+			preview.Added = append(preview.Added, path)
+		}
+	}
+	return preview, nil
+}
+
+// ApplyScan stores scan options to the map and adds files of the plan
+// which are not in the map yet. In sync mode, excluded and missing
+// resources are removed or archived, and archived resources found
+// again are restored.
+func (p *ProxyMap) ApplyScan(opts ScanOptions, plan *ScanPlan) *ScanResult {
+	p.Read()
+	p.Exclude = opts.Exclude
+	p.IgnoreFiles = opts.IgnoreFiles
+	p.FollowSymlinks = opts.FollowSymlinks
+	p.Filter = opts.Filter
+	p.ScanSource = opts.Source
+	p.ScanUntracked = opts.Untracked
+	p.Changed = true
+	p.restartWatch()
+
+	result := &ScanResult{
+		Removed:   make([]StaleResource, 0),
+		Archived:  make([]StaleResource, 0),
+		Restored:  make([]ResourceID, 0),
+		Excluded:  plan.Excluded,
+		Truncated: plan.Truncated,
+		Source:    plan.Source,
+	}
+	if opts.Sync {
+		p.syncStale(opts, plan, result)
+	}
+
+	byPath := make(map[string]*Resource)
+	for _, rsrc := range p.Resources {
+		byPath[rsrc.Path] = rsrc
+	}
+	for _, path := range plan.Paths {
+		if existing := byPath[path]; existing != nil {
+			if opts.Sync && existing.Archived {
+				existing.Archived = false
+				result.Restored = append(result.Restored, existing.ResourceID)
+			}
+			continue
+		}
+		rsrc := &Resource{
+			Type:   ResourceFile,
+			Path:   path,
+			Target: plan.Targets[path],
+		}
+		p.AddResource(rsrc)
+		p.AssignResourceStyle(rsrc)
+		result.Resources = append(result.Resources, rsrc)
+	}
+	p.AssignPositions(result.Resources)
+	p.AssignLayers(result.Resources)
+	return result
+}
+
+// syncStale removes or archives excluded and missing resources.
+// Resources skipped by the scan are kept.
+func (p *ProxyMap) syncStale(opts ScanOptions, plan *ScanPlan, result *ScanResult) {
+	for _, s := range p.staleResources(opts, plan) {
+		if s.Reason == StaleSkipped {
+			continue
+		}
+		if !opts.Archive {
+			p.DeleteResource(s.ID)
+			result.Removed = append(result.Removed, s)
+			continue
+		}
+		if rsrc := p.GetResource(s.ID); rsrc != nil && !rsrc.Archived {
+			rsrc.Archived = true
+			result.Archived = append(result.Archived, s)
+		}
+	}
+}
+
+// staleResources returns resources under scanned path which are not
+// found by the scan.
+func (p *ProxyMap) staleResources(opts ScanOptions, plan *ScanPlan) []StaleResource {
+	found := make(map[string]bool)
+	for _, path := range plan.Paths {
+		found[path] = true
+	}
+	exclusions := make(map[string]*scanner.Exclusion)
+	for i := range plan.Excluded {
+		exclusions[plan.Excluded[i].Path] = &plan.Excluded[i]
+	}
+
+	scanned, err := filepath.Rel(p.Base, opts.Path)
+	if err != nil {
+		scanned = "."
+	}
+	stale := make([]StaleResource, 0)
+	for _, rsrc := range p.Resources {
+		if found[rsrc.Path] || !isUnderDir(rsrc.Path, scanned) {
+			continue
+		}
+		s := StaleResource{
+			ID:        rsrc.ResourceID,
+			Path:      rsrc.Path,
+			Exclusion: findExclusion(exclusions, filepath.ToSlash(rsrc.Path)),
+		}
+		if s.Exclusion != nil {
+			s.Reason = StaleExcluded
+		} else if _, err := os.Lstat(filepath.Join(p.Base, rsrc.Path)); os.IsNotExist(err) {
+			s.Reason = StaleMissing
+		} else {
+			s.Reason = StaleSkipped
+		}
+		stale = append(stale, s)
+	}
+	return stale
+}
+
+// findExclusion returns exclusion of slash separated path or its
+// parent directory, or nil if path is not excluded.
+func findExclusion(exclusions map[string]*scanner.Exclusion, path string) *scanner.Exclusion {
+	for {
+		if e, ok := exclusions[path]; ok {
+			return e
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			return nil
+		}
+		path = path[:i]
+	}
+}
+
+// isUnderDir tells if relative path is inside relative dir.
+func isUnderDir(path string, dir string) bool {
+	if dir == "." {
+		return true
+	}
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// resourcePaths returns set of resource paths in the map.
+func (p *ProxyMap) resourcePaths() map[string]bool {
+	p.Read()
+	paths := make(map[string]bool)
+	for _, rsrc := range p.Resources {
+		paths[rsrc.Path] = true
+	}
+	return paths
+}
+
+// walk starts scanner with given options.
+func (p *ProxyMap) walk(ctx context.Context, opts ScanOptions) *scanner.Walker {
+	return scanner.Walk(ctx, opts.Path, p.Base, opts.scannerOptions())
+}
+
+// scannerOptions returns options for scanner package.
+func (opts ScanOptions) scannerOptions() scanner.Options {
+	return scanner.Options{
+		Exclude:        opts.Exclude,
+		IgnoreFiles:    opts.IgnoreFiles,
+		FollowSymlinks: opts.FollowSymlinks,
+		MaxFiles:       opts.MaxFiles,
+		MaxDepth:       opts.MaxDepth,
+		Filter:         opts.Filter,
+		Source:         opts.Source,
+		Untracked:      opts.Untracked,
+	}
+}
+
+// collectScan reads items of walker to a plan.
+// onFile is called for each found file, if given.
+func collectScan(walker *scanner.Walker, base string, onFile func(path string)) *ScanPlan {
+	plan := &ScanPlan{
+		Targets:  make(map[string]string),
+		Excluded: make([]scanner.Exclusion, 0),
+	}
+	for item := range walker.Items {
+		if item.Excluded != nil {
+			plan.Excluded = append(plan.Excluded, *item.Excluded)
+			continue
+		}
+		// convert absolute path to relative
+		path, err := filepath.Rel(base, item.File)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"basepath": base,
+				"targpath": item.File,
+			}).Error("Scan: Could not make relative path")
+			path = item.File
+		}
+		plan.Paths = append(plan.Paths, path)
+		if item.Target != "" {
+			plan.Targets[path] = item.Target
+		}
+		if onFile != nil {
+			onFile(path)
+		}
+	}
+	scanner.SortPaths(plan.Paths)
+	plan.Truncated = walker.Truncated()
+	plan.Source = walker.Source()
+	return plan
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreviewScan(t *testing.T) {
+	pm := getTestScanMap(t, []string{"a.go", "b.go", "build/out.go"})
+	defer os.RemoveAll(pm.Base)
+	for _, path := range []string{"a.go", "build/out.go", "gone.go"} {
+		pm.AddResource(&Resource{Path: filepath.FromSlash(path)})
+	}
+	pm.Exclude = []string{"*.tmp"}
+
+	opts := pm.GetScanOptions()
+	opts.Exclude = []string{"build/"}
+	preview, err := pm.PreviewScan(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(preview.Added) != 1 || preview.Added[0] != "b.go" {
+		t.Error("Expected b.go to be added, got", preview.Added)
+	}
+	reasons := make(map[string]string)
+	for _, s := range preview.Stale {
+		reasons[filepath.ToSlash(s.Path)] = s.Reason
+	}
+	if reasons["build/out.go"] != StaleExcluded || reasons["gone.go"] != StaleMissing || len(reasons) != 2 {
+		t.Error("Expected excluded and missing stale resources, got", preview.Stale)
+	}
+
+	if len(pm.Resources) != 3 || len(pm.Exclude) != 1 || pm.Exclude[0] != "*.tmp" {
+		t.Error("Expected preview not to modify the map")
+	}
+	if _, err := os.Stat(filepath.Join(pm.Base, pm.File)); !os.IsNotExist(err) {
+		t.Error("Expected preview not to write the map file")
+	}
+}
+
+func TestScanSync(t *testing.T) {
+	pm := getTestScanMap(t, []string{"a.go", "build/out.go"})
+	defer os.RemoveAll(pm.Base)
+	for _, path := range []string{"a.go", "build/out.go", "gone.go"} {
+		pm.AddResource(&Resource{Path: filepath.FromSlash(path)})
+	}
+
+	opts := pm.GetScanOptions()
+	opts.Exclude = []string{"build/"}
+	opts.Sync = true
+	opts.Archive = true
+	result, err := pm.Scan(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Archived) != 2 || len(pm.Resources) != 3 {
+		t.Error("Expected 2 archived resources, got", result.Archived)
+	}
+
+	// archived resource is restored when it is found again
+	opts.Exclude = nil
+	if result, err = pm.Scan(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	out := pm.GetResourceByPath(filepath.FromSlash("build/out.go"))
+	if len(result.Restored) != 1 || out.Archived {
+		t.Error("Expected build/out.go to be restored, got", result.Restored)
+	}
+
+	opts.Archive = false
+	if result, err = pm.Scan(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 1 || pm.GetResourceByPath("gone.go") != nil {
+		t.Error("Expected gone.go to be removed, got", result.Removed)
+	}
+}
+
+// getTestScanMap creates empty files to a new temporary dir and
+// returns map based in the dir.
+func getTestScanMap(t *testing.T, files []string) *ProxyMap {
+	base, err := ioutil.TempDir("", "filemaps-scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		path := filepath.Join(base, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pm := NewProxyMap(MapInfo{Base: base, File: "test.filemap"})
+	pm.IsRead = true
+	pm.refreshResourceIdx()
+	return pm
+}
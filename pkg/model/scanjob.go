@@ -0,0 +1,210 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"context"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/config"
+	"github.com/filemaps/filemaps/pkg/scanner"
+)
+
+const (
+	// scanJobIDLength is length of random scan job IDs.
+	scanJobIDLength = 12
+	// scanJobExpiry is how long finished jobs are kept for polling.
+	scanJobExpiry = time.Hour
+)
+
+var (
+	// ErrScanRunning is returned when map already has a running scan.
+	ErrScanRunning = errors.New("scan already running")
+
+	scanJobsMu sync.Mutex
+	scanJobs   = make(map[string]*ScanJob) // job ID -> job
+)
+
+// ScanJobState tells state of a ScanJob.
+type ScanJobState string
+
+// Scan job states
+const (
+	ScanRunning   ScanJobState = "running"
+	ScanDone      ScanJobState = "done"
+	ScanCancelled ScanJobState = "cancelled"
+	ScanFailed    ScanJobState = "failed"
+)
+
+// ScanStatus is a snapshot of ScanJob progress.
+type ScanStatus struct {
+	ID      string       `json:"id"`
+	MapID   int          `json:"mapId"`
+	State   ScanJobState `json:"state"`
+	Visited int          `json:"visited"`
+	// Added is number of new files found, or added when done
+	Added       int          `json:"added"`
+	CurrentDir  string       `json:"currentDir"`
+	Error       string       `json:"error"`
+	Started     time.Time    `json:"started"`
+	Finished    time.Time    `json:"finished"`
+	ResourceIDs []ResourceID `json:"resourceIds"`
+	Result      *ScanResult  `json:"result"`
+}
+
+// ScanJob runs a scan in background and applies the result to the map
+// when the scan is done.
+type ScanJob struct {
+	pm       *ProxyMap
+	opts     ScanOptions
+	existing map[string]bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+	added    int64
+
+	mu     sync.Mutex
+	status ScanStatus
+	walker *scanner.Walker
+}
+
+// StartScanJob starts scanning files to the map in background.
+// Map must be locked by the caller.
+func StartScanJob(pm *ProxyMap, opts ScanOptions) (*ScanJob, error) {
+	scanJobsMu.Lock()
+	defer scanJobsMu.Unlock()
+
+	now := time.Now()
+	for id, j := range scanJobs {
+		s := j.Status()
+		if s.MapID == pm.ID && s.State == ScanRunning {
+			return nil, ErrScanRunning
+		}
+		if s.State != ScanRunning && now.Sub(s.Finished) > scanJobExpiry {
+			delete(scanJobs, id)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &ScanJob{
+		pm:       pm,
+		opts:     opts,
+		existing: pm.resourcePaths(),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		status: ScanStatus{
+			ID:      randString(scanJobIDLength),
+			MapID:   pm.ID,
+			State:   ScanRunning,
+			Started: now,
+		},
+	}
+	j.walker = pm.walk(ctx, opts)
+	scanJobs[j.status.ID] = j
+
+	log.WithFields(log.Fields{
+		"id":    j.status.ID,
+		"mapId": pm.ID,
+		"path":  opts.Path,
+	}).Info("Scan job started")
+
+	go j.run()
+	return j, nil
+}
+
+// GetScanJob returns scan job by ID or nil if not found.
+func GetScanJob(id string) *ScanJob {
+	scanJobsMu.Lock()
+	defer scanJobsMu.Unlock()
+	return scanJobs[id]
+}
+
+// Status returns current status of the job.
+func (j *ScanJob) Status() ScanStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.status
+	if s.State == ScanRunning {
+		s.Visited, s.CurrentDir = j.walker.Progress()
+		s.Added = int(atomic.LoadInt64(&j.added))
+	}
+	return s
+}
+
+// Cancel stops the job. Nothing is applied to the map
+// if the scan is not done yet.
+func (j *ScanJob) Cancel() {
+	j.cancel()
+}
+
+// Done returns channel which is closed when the job is finished.
+func (j *ScanJob) Done() <-chan struct{} {
+	return j.done
+}
+
+func (j *ScanJob) run() {
+	defer close(j.done)
+	defer j.cancel()
+
+	plan := collectScan(j.walker, j.pm.Base, func(path string) {
+		if !j.existing[path] {
+			atomic.AddInt64(&j.added, 1)
+		}
+	})
+	if err := j.walker.Err(); err == config.ErrPathNotAllowed {
+		j.finish(ScanFailed, nil, err)
+		return
+	} else if err != nil {
+		j.finish(ScanCancelled, nil, err)
+		return
+	}
+
+	// apply all at once so clients never see a partial scan
+	j.pm.Lock()
+	result := j.pm.ApplyScan(j.opts, plan)
+	err := j.pm.Write()
+	j.pm.Unlock()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id":  j.status.ID,
+			"err": err,
+		}).Error("Could not write map after scan")
+		j.finish(ScanFailed, result, err)
+		return
+	}
+	j.finish(ScanDone, result, nil)
+}
+
+// finish stores final status of the job.
+func (j *ScanJob) finish(state ScanJobState, result *ScanResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := &j.status
+	s.State = state
+	s.Finished = time.Now()
+	s.Visited, s.CurrentDir = j.walker.Progress()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.Result = result
+	s.Added = 0
+	if result != nil {
+		s.Added = len(result.Resources)
+		for _, rsrc := range result.Resources {
+			s.ResourceIDs = append(s.ResourceIDs, rsrc.ResourceID)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"id":      s.ID,
+		"state":   s.State,
+		"visited": s.Visited,
+		"added":   s.Added,
+	}).Info("Scan job finished")
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanJob(t *testing.T) {
+	pm := getTestScanMap(t, []string{"a.go", "b.go", "sub/c.go"})
+	defer os.RemoveAll(pm.Base)
+	pm.AddResource(&Resource{Path: "a.go"})
+
+	pm.Lock()
+	job, err := StartScanJob(pm, pm.GetScanOptions())
+	pm.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-job.Done()
+
+	s := job.Status()
+	if s.State != ScanDone {
+		t.Fatal("Expected scan job to be done, got", s.State, s.Error)
+	}
+	if s.Added != 2 || len(s.ResourceIDs) != 2 {
+		t.Error("Expected 2 added resources, got", s.Added, s.ResourceIDs)
+	}
+	if s.Visited != 3 {
+		t.Error("Expected 3 visited files, got", s.Visited)
+	}
+	if pm.GetResourceByPath(filepath.FromSlash("sub/c.go")) == nil {
+		t.Error("Expected scanned file to be added to map")
+	}
+	if GetScanJob(s.ID) != job {
+		t.Error("Expected job to be found by ID")
+	}
+}
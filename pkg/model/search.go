@@ -0,0 +1,149 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"context"
+	log "github.com/Sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/filemaps/filemaps/pkg/config"
+	"github.com/filemaps/filemaps/pkg/search"
+)
+
+const (
+	// SearchDirName is directory under config dir for search indexes.
+	SearchDirName = "search"
+	// searchIndexExt is file name extension for search index files.
+	searchIndexExt = ".index"
+)
+
+var (
+	searchIndexesMu sync.Mutex
+	searchIndexes   = make(map[int]*search.Index) // map ID -> index
+)
+
+// SearchMatch contains matching lines of a resource file.
+type SearchMatch struct {
+	ResourceID ResourceID `json:"resourceId"`
+	search.FileMatch
+}
+
+// SearchResult contains matches of a full-text search.
+type SearchResult struct {
+	Matches []SearchMatch `json:"matches"`
+	// Total is number of all matching lines
+	Total int `json:"total"`
+	// Searched is number of files read, Files number of files
+	// of the map
+	Searched int `json:"searched"`
+	Files    int `json:"files"`
+	// Truncated tells if there are more matches than returned
+	Truncated bool `json:"truncated"`
+}
+
+// Search searches text in files of the map. Archived and missing
+// resources and files outside allowed roots are not searched.
+// If useIndex is set, files are skipped with trigram index stored
+// in config dir.
+func (p *ProxyMap) Search(ctx context.Context, opts search.Options, useIndex bool) (*SearchResult, error) {
+	p.Read()
+	if err := config.CheckPath(p.Base); err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string]ResourceID)
+	var paths []string
+	for _, rsrc := range p.Resources {
+		if rsrc.Type != ResourceFile || rsrc.Archived || rsrc.Missing {
+			continue
+		}
+		if config.CheckPath(filepath.Join(p.Base, rsrc.Path)) != nil {
+			continue
+		}
+		byPath[rsrc.Path] = rsrc.ResourceID
+		paths = append(paths, rsrc.Path)
+	}
+
+	var idx *search.Index
+	if useIndex {
+		var err error
+		if idx, err = p.getSearchIndex(); err != nil {
+			log.WithFields(log.Fields{
+				"id":  p.ID,
+				"err": err,
+			}).Error("Could not read search index")
+		}
+		opts.Index = idx
+	}
+
+	sr, err := search.Search(ctx, p.Base, paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	if idx != nil {
+		idx.Prune(paths)
+		if err := idx.Write(); err != nil {
+			log.WithFields(log.Fields{
+				"id":  p.ID,
+				"err": err,
+			}).Error("Could not write search index")
+		}
+	}
+
+	result := &SearchResult{
+		Matches:   make([]SearchMatch, 0, len(sr.Files)),
+		Total:     sr.Total,
+		Searched:  sr.Searched,
+		Files:     len(paths),
+		Truncated: sr.Truncated,
+	}
+	for _, fm := range sr.Files {
+		result.Matches = append(result.Matches, SearchMatch{
+			ResourceID: byPath[fm.Path],
+			FileMatch:  fm,
+		})
+	}
+	return result, nil
+}
+
+// getSearchIndex returns search index of the map, reading it from
+// config dir if needed.
+func (p *ProxyMap) getSearchIndex() (*search.Index, error) {
+	searchIndexesMu.Lock()
+	defer searchIndexesMu.Unlock()
+	if idx := searchIndexes[p.ID]; idx != nil && idx.Base() == p.Base {
+		return idx, nil
+	}
+	idx, err := search.OpenIndex(getSearchIndexPath(p.ID), p.Base)
+	if err != nil {
+		return nil, err
+	}
+	searchIndexes[p.ID] = idx
+	return idx, nil
+}
+
+// deleteSearchIndex removes search index of the map.
+func (p *ProxyMap) deleteSearchIndex() {
+	searchIndexesMu.Lock()
+	delete(searchIndexes, p.ID)
+	searchIndexesMu.Unlock()
+	err := os.Remove(getSearchIndexPath(p.ID))
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"id":  p.ID,
+			"err": err,
+		}).Error("Could not remove search index")
+	}
+}
+
+// getSearchIndexPath returns path of search index file of map.
+func getSearchIndexPath(mapID int) string {
+	return filepath.Join(config.GetDir(), SearchDirName, strconv.Itoa(mapID)+searchIndexExt)
+}
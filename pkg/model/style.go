@@ -5,8 +5,102 @@
 
 package model
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrStyleNotFound is returned when map has no style for class.
+	ErrStyleNotFound = errors.New("style not found")
+	// ErrStyleExists is returned when adding style for existing class.
+	ErrStyleExists = errors.New("style already exists")
+
+	sclassRegexp = regexp.MustCompile(`^-?[_a-zA-Z][_a-zA-Z0-9-]*$`)
+)
+
 // Style defines graphical style rules.
 type Style struct {
 	SClass string            `json:"sClass"`
 	Rules  map[string]string `json:"rules"`
 }
+
+// Validate returns error if style class is not a valid CSS class name
+// or any of the rules is rejected by CSS sanitizer.
+func (s *Style) Validate() error {
+	if !sclassRegexp.MatchString(s.SClass) {
+		return fmt.Errorf("invalid style class %q", s.SClass)
+	}
+	return checkRules("style "+s.SClass, s.Rules)
+}
+
+// AddStyle adds new style to map.
+func (p *ProxyMap) AddStyle(s Style) error {
+	p.Read()
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if p.findStyle(s.SClass) >= 0 {
+		return ErrStyleExists
+	}
+	if s.Rules == nil {
+		s.Rules = make(map[string]string)
+	}
+	p.Styles = append(p.Styles, s)
+	p.Changed = true
+	return nil
+}
+
+// UpdateStyle replaces rules of existing map style.
+func (p *ProxyMap) UpdateStyle(s Style) error {
+	p.Read()
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	i := p.findStyle(s.SClass)
+	if i < 0 {
+		return ErrStyleNotFound
+	}
+	if s.Rules == nil {
+		s.Rules = make(map[string]string)
+	}
+	p.Styles[i] = s
+	p.Changed = true
+	return nil
+}
+
+// DeleteStyle deletes map style. Resources of the class fall back to
+// theme style.
+func (p *ProxyMap) DeleteStyle(sclass string) error {
+	p.Read()
+	i := p.findStyle(sclass)
+	if i < 0 {
+		return ErrStyleNotFound
+	}
+	p.Styles = append(p.Styles[:i], p.Styles[i+1:]...)
+	p.Changed = true
+	return nil
+}
+
+// GetResourcesByClass returns resources whose resolved style class
+// is sclass, with their resolved styles.
+func (p *ProxyMap) GetResourcesByClass(sclass string) []StyledResource {
+	var found []StyledResource
+	for _, sr := range p.StyledResources(p.Resources) {
+		if sr.ResolvedStyle.SClass == sclass {
+			found = append(found, sr)
+		}
+	}
+	return found
+}
+
+// findStyle returns index of map style with given class or -1.
+func (p *ProxyMap) findStyle(sclass string) int {
+	for i := range p.Styles {
+		if p.Styles[i].SClass == sclass {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestStyleValidate(t *testing.T) {
+	var tests = []struct {
+		sclass string
+		valid  bool
+	}{
+		{"go", true},
+		{"my-class_2", true},
+		{"-webkit", true},
+		{"_private", true},
+		{"", false},
+		{"2go", false},
+		{"--go", false},
+		{"go lang", false},
+		{"go{color:red}", false},
+	}
+	for _, test := range tests {
+		s := Style{SClass: test.sclass}
+		if err := s.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate %q returned %v", test.sclass, err)
+		}
+	}
+
+	s := Style{SClass: "go", Rules: map[string]string{"color": "red", "behavior": "url(x.htc)"}}
+	if err := s.Validate(); err == nil {
+		t.Error("Expected error for rejected rule")
+	}
+}
+
+func TestStyleCRUD(t *testing.T) {
+	defer setTestConfigDir(t)()
+	pm := getTestProxyMap()
+
+	if err := pm.AddStyle(Style{SClass: "go"}); err != nil {
+		t.Fatal(err)
+	}
+	if pm.Styles[0].Rules == nil {
+		t.Error("Expected added style to have rules map")
+	}
+	if err := pm.AddStyle(Style{SClass: "go"}); err != ErrStyleExists {
+		t.Errorf("Expected ErrStyleExists for duplicate class, got %v", err)
+	}
+	if err := pm.AddStyle(Style{SClass: "2go"}); err == nil {
+		t.Error("Expected error for invalid class")
+	}
+
+	style := Style{SClass: "go", Rules: map[string]string{"color": "#123456"}}
+	if err := pm.UpdateStyle(style); err != nil {
+		t.Fatal(err)
+	}
+	if s := pm.GetClassStyle("go"); s == nil || s.Rules["color"] != "#123456" {
+		t.Errorf("Expected updated style, got %+v", s)
+	}
+	if err := pm.UpdateStyle(Style{SClass: "rust"}); err != ErrStyleNotFound {
+		t.Errorf("Expected ErrStyleNotFound, got %v", err)
+	}
+
+	// deleting a class in use falls back to theme style
+	pm.AddResource(&Resource{Path: "main.go", Style: Style{SClass: "go"}})
+	pm.AddResource(&Resource{Path: "README.md", Style: Style{SClass: "md"}})
+	if found := pm.GetResourcesByClass("go"); len(found) != 1 || found[0].ResolvedStyle.Rules["color"] != "#123456" {
+		t.Fatalf("Expected 1 resource with map style, got %+v", found)
+	}
+	if err := pm.DeleteStyle("go"); err != nil {
+		t.Fatal(err)
+	}
+	if len(pm.Styles) != 0 {
+		t.Errorf("Expected no map styles, got %+v", pm.Styles)
+	}
+	theme := GetTheme(DefaultThemeID)
+	var themeColor string
+	for _, s := range theme.Styles {
+		if s.SClass == "go" {
+			themeColor = s.Rules["color"]
+		}
+	}
+	if themeColor == "" {
+		t.Fatal("Expected default theme to style go")
+	}
+	found := pm.GetResourcesByClass("go")
+	if len(found) != 1 || found[0].ResolvedStyle.Rules["color"] != themeColor {
+		t.Errorf("Expected resource to fall back to theme style, got %+v", found)
+	}
+	if err := pm.DeleteStyle("go"); err != ErrStyleNotFound {
+		t.Errorf("Expected ErrStyleNotFound, got %v", err)
+	}
+}
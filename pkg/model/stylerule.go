@@ -0,0 +1,248 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StyleMatch defines conditions for StyleRule. Empty conditions match
+// any resource, all given conditions must match.
+type StyleMatch struct {
+	// Path is a glob relative to map base, "**" matches any number
+	// of directories
+	Path string `json:"path,omitempty"`
+	// Dir matches resources under given directory relative to map base
+	Dir  string        `json:"dir,omitempty"`
+	Tag  string        `json:"tag,omitempty"`
+	Type *ResourceType `json:"type,omitempty"`
+	// MinSize and MaxSize are file sizes in bytes
+	MinSize int64 `json:"minSize,omitempty"`
+	MaxSize int64 `json:"maxSize,omitempty"`
+	// OlderThan and NewerThan are file modification ages in days
+	OlderThan float64 `json:"olderThan,omitempty"`
+	NewerThan float64 `json:"newerThan,omitempty"`
+}
+
+// StyleRule assigns style for resources matching its conditions.
+type StyleRule struct {
+	Name string `json:"name"`
+	// Priority defines precedence, rules with higher priority
+	// override rules with lower priority
+	Priority int               `json:"priority"`
+	Match    StyleMatch        `json:"match"`
+	SClass   string            `json:"sClass"`
+	Rules    map[string]string `json:"rules"`
+}
+
+// StyledResource is Resource with its resolved style.
+type StyledResource struct {
+	*Resource
+	ResolvedStyle Style `json:"resolvedStyle"`
+}
+
+// Validate returns error if rule has invalid conditions.
+func (sr *StyleRule) Validate() error {
+	m := sr.Match
+	if m.Path != "" {
+		for _, part := range strings.Split(filepath.ToSlash(m.Path), "/") {
+			if _, err := filepath.Match(part, ""); err != nil {
+				return fmt.Errorf("invalid path pattern %q", m.Path)
+			}
+		}
+	}
+	if m.MinSize < 0 || m.MaxSize < 0 || (m.MaxSize > 0 && m.MinSize > m.MaxSize) {
+		return fmt.Errorf("invalid size range in rule %q", sr.Name)
+	}
+	if m.OlderThan < 0 || m.NewerThan < 0 {
+		return fmt.Errorf("invalid age in rule %q", sr.Name)
+	}
+	if sr.SClass != "" && !sclassRegexp.MatchString(sr.SClass) {
+		return fmt.Errorf("invalid style class %q", sr.SClass)
+	}
+	return checkRules("style rule "+sr.Name, sr.Rules)
+}
+
+// needsFileInfo returns true if matching requires file info.
+func (m *StyleMatch) needsFileInfo() bool {
+	return m.MinSize > 0 || m.MaxSize > 0 || m.OlderThan > 0 || m.NewerThan > 0
+}
+
+// matches returns true if resource fulfills all conditions.
+// info may be nil if file could not be read.
+func (m *StyleMatch) matches(r *Resource, info os.FileInfo) bool {
+	path := filepath.ToSlash(r.Path)
+	if m.Path != "" && !matchPathGlob(filepath.ToSlash(m.Path), path) {
+		return false
+	}
+	if m.Dir != "" {
+		dir := strings.Trim(filepath.ToSlash(m.Dir), "/")
+		if dir != "." && !strings.HasPrefix(path, dir+"/") {
+			return false
+		}
+	}
+	if m.Tag != "" && !r.HasTag(m.Tag) {
+		return false
+	}
+	if m.Type != nil && *m.Type != r.Type {
+		return false
+	}
+	if !m.needsFileInfo() {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+	if m.MinSize > 0 && info.Size() < m.MinSize {
+		return false
+	}
+	if m.MaxSize > 0 && info.Size() > m.MaxSize {
+		return false
+	}
+	age := time.Since(info.ModTime()).Hours() / 24
+	if m.OlderThan > 0 && age <= m.OlderThan {
+		return false
+	}
+	if m.NewerThan > 0 && age >= m.NewerThan {
+		return false
+	}
+	return true
+}
+
+// ResolveStyle computes the final style for resource.
+// Precedence from lowest to highest:
+//  1. map style (or theme style) of resource's style class
+//  2. matching style rules in ascending priority, for equal priority
+//     in the order they are defined
+//  3. rules defined in the resource itself
+//
+// The last style class given by a matching rule replaces resource's class.
+func (p *ProxyMap) ResolveStyle(r *Resource) Style {
+	return p.resolveStyle(r, p.GetTheme(), p.sortedStyleRules())
+}
+
+func (p *ProxyMap) resolveStyle(r *Resource, theme *Theme, rules []*StyleRule) Style {
+
+	var info os.FileInfo
+	for _, sr := range rules {
+		if sr.Match.needsFileInfo() {
+			info, _ = os.Stat(filepath.Join(p.Base, r.Path))
+			break
+		}
+	}
+
+	sclass := r.Style.SClass
+	var matched []*StyleRule
+	for _, sr := range rules {
+		if sr.Match.matches(r, info) {
+			matched = append(matched, sr)
+			if sr.SClass != "" {
+				sclass = sr.SClass
+			}
+		}
+	}
+
+	resolved := Style{
+		SClass: sclass,
+		Rules:  make(map[string]string),
+	}
+	if s := p.classStyle(sclass, theme); s != nil {
+		mergeRules(resolved.Rules, s.Rules)
+	}
+	for _, sr := range matched {
+		mergeRules(resolved.Rules, sr.Rules)
+	}
+	mergeRules(resolved.Rules, r.Style.Rules)
+	return resolved
+}
+
+// StyledResources returns given resources with their resolved styles.
+func (p *ProxyMap) StyledResources(resources []*Resource) []StyledResource {
+	theme := p.GetTheme()
+	rules := p.sortedStyleRules()
+	styled := make([]StyledResource, 0, len(resources))
+	for _, rsrc := range resources {
+		styled = append(styled, StyledResource{
+			Resource:      rsrc,
+			ResolvedStyle: p.resolveStyle(rsrc, theme, rules),
+		})
+	}
+	return styled
+}
+
+// GetClassStyle returns map style for style class, or style from map's
+// theme if map does not define it. Returns nil if class has no style.
+func (p *ProxyMap) GetClassStyle(sclass string) *Style {
+	return p.classStyle(sclass, p.GetTheme())
+}
+
+func (p *ProxyMap) classStyle(sclass string, theme *Theme) *Style {
+	if sclass == "" {
+		return nil
+	}
+	if i := p.findStyle(sclass); i >= 0 {
+		return &p.Styles[i]
+	}
+	for i := range theme.Styles {
+		if theme.Styles[i].SClass == sclass {
+			return &theme.Styles[i]
+		}
+	}
+	return nil
+}
+
+// sortedStyleRules returns style rules in ascending priority.
+func (p *ProxyMap) sortedStyleRules() []*StyleRule {
+	p.Read()
+	rules := make([]*StyleRule, len(p.StyleRules))
+	for i := range p.StyleRules {
+		rules[i] = &p.StyleRules[i]
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority < rules[j].Priority
+	})
+	return rules
+}
+
+// mergeRules copies rules from src to dst, overriding existing ones.
+func mergeRules(dst map[string]string, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+// matchPathGlob matches slash separated path against glob pattern.
+// Pattern segment "**" matches zero or more directories.
+func matchPathGlob(pattern string, path string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern []string, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		path = path[1:]
+	}
+	return len(path) == 0
+}
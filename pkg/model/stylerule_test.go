@@ -0,0 +1,65 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestMatchPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "cmd/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"internal/**", "internal/auth/token.go", true},
+		{"internal/**/token.go", "internal/token.go", true},
+		{"internal/**/token.go", "pkg/internal/token.go", false},
+	}
+	for _, test := range tests {
+		if matchPathGlob(test.pattern, test.path) != test.match {
+			t.Errorf("matchPathGlob(%q, %q) != %v", test.pattern, test.path, test.match)
+		}
+	}
+}
+
+func TestResolveStyle(t *testing.T) {
+	pm := getTestProxyMap()
+	pm.StyleRules = []StyleRule{
+		{
+			Priority: 10,
+			Match:    StyleMatch{Dir: "internal/auth"},
+			Rules:    map[string]string{"color": "#ff0000"},
+		},
+		{
+			Priority: 1,
+			Match:    StyleMatch{Tag: "todo"},
+			SClass:   "todo",
+			Rules:    map[string]string{"color": "#00ff00", "opacity": "0.5"},
+		},
+	}
+	rsrc := &Resource{
+		Path:  "internal/auth/token.go",
+		Style: Style{SClass: "go"},
+		Tags:  []string{"todo"},
+	}
+	pm.AddResource(rsrc)
+
+	s := pm.ResolveStyle(rsrc)
+	if s.SClass != "todo" {
+		t.Error("Expected style class todo, got", s.SClass)
+	}
+	if s.Rules["color"] != "#ff0000" {
+		t.Error("Expected higher priority rule to win, got", s.Rules["color"])
+	}
+	if s.Rules["opacity"] != "0.5" {
+		t.Error("Expected lower priority rule to be merged, got", s.Rules["opacity"])
+	}
+}
@@ -5,8 +5,364 @@
 
 package model
 
-// Template for Map.
-type Template struct {
-	id   string `json:"id"`
-	name string `json:"name"`
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/filemaps/filemaps/pkg/config"
+)
+
+const (
+	// TemplateVersion defines current Template file version.
+	TemplateVersion = 1
+	// TemplatesDirName is directory under config dir for template files.
+	TemplatesDirName = "templates"
+	// templateFileExt is file name extension for template files.
+	templateFileExt = ".json"
+)
+
+// TemplateV1 is first version of Template struct.
+type TemplateV1 struct {
+	Version     int             `json:"version"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Exclude     []string        `json:"exclude"`
+	Styles      []Style         `json:"styles"`
+	StyleRules  []StyleRule     `json:"styleRules"`
+	Zones       []*Zone2D       `json:"zones"`
+	NewZone     *OpenZone2D     `json:"newZone"`
+	Placements  []PlacementRule `json:"placements"`
+	LayerMode   LayerMode       `json:"layerMode"`
+	Theme       string          `json:"theme"`
+	BuiltIn     bool            `json:"builtIn"`
+}
+
+// Template presets structure for a new Map.
+type Template TemplateV1
+
+// NewBuiltInTemplates returns templates bundled with File Maps.
+func NewBuiltInTemplates() []*Template {
+	testRule := StyleRule{
+		Name:     "Tests",
+		Priority: 10,
+		Rules: map[string]string{
+			"opacity": "0.6",
+		},
+	}
+
+	goTests := testRule
+	goTests.Match = StyleMatch{Path: "**/*_test.go"}
+
+	goService := &Template{
+		Version:     TemplateVersion,
+		ID:          "go-service",
+		Name:        "Go service",
+		Description: "Go module with commands, packages and API definitions",
+		Exclude: []string{
+			"vendor/", "bin/", "dist/", "*.exe", "*.test", "*.out", "*.prof",
+		},
+		Styles:     make([]Style, 0),
+		StyleRules: []StyleRule{goTests},
+		Zones: []*Zone2D{
+			newRectZone(2, "cmd", -2000, 1500, 1500, 1000),
+			newRectZone(3, "pkg", -2000, 300, 1500, 1000),
+			newRectZone(4, "internal", -2000, -900, 1500, 1000),
+			newRectZone(5, "api", -300, 1500, 1000, 1000),
+		},
+		NewZone: newNewZone(800, 1500, 800, OpenDown),
+	}
+
+	jsTests := testRule
+	jsTests.Match = StyleMatch{Path: "**/*.test.*"}
+	jsSpecs := testRule
+	jsSpecs.Match = StyleMatch{Path: "**/*.spec.*"}
+
+	frontendApp := &Template{
+		Version:     TemplateVersion,
+		ID:          "frontend-app",
+		Name:        "Frontend app",
+		Description: "JavaScript or TypeScript single page application",
+		Exclude: []string{
+			"node_modules/", "dist/", "build/", "coverage/", ".cache/",
+			".next/", ".nuxt/", "*.map", "*.min.js", "*.min.css",
+		},
+		Styles:     make([]Style, 0),
+		StyleRules: []StyleRule{jsTests, jsSpecs},
+		Zones: []*Zone2D{
+			newRectZone(2, "src", -2000, 1500, 2000, 1500),
+			newRectZone(3, "public", 200, 1500, 1000, 700),
+			newRectZone(4, "tests", 200, 600, 1000, 600),
+		},
+		NewZone: newNewZone(1500, 1500, 800, OpenDown),
+	}
+
+	monorepo := &Template{
+		Version:     TemplateVersion,
+		ID:          "monorepo",
+		Name:        "Monorepo",
+		Description: "Many services and libraries in one repository, one layer per top-level directory",
+		Exclude: []string{
+			"node_modules/", "vendor/", "dist/", "build/", "target/",
+			"bazel-*", ".terraform/", "*.pyc", "__pycache__/",
+		},
+		Styles:     make([]Style, 0),
+		StyleRules: make([]StyleRule, 0),
+		Zones: []*Zone2D{
+			newRectZone(2, "services", -3000, 2000, 3000, 2000),
+			newRectZone(3, "libs", 200, 2000, 2000, 2000),
+			newRectZone(4, "tools", -3000, -200, 2000, 1000),
+			newRectZone(5, "docs", -800, -200, 1500, 1000),
+		},
+		NewZone:   newNewZone(2500, 2000, 1000, OpenDown),
+		LayerMode: LayerPackage,
+	}
+
+	templates := []*Template{goService, frontendApp, monorepo}
+	for _, t := range templates {
+		t.BuiltIn = true
+	}
+	return templates
+}
+
+// GetTemplates returns built-in templates followed by templates stored
+// in config dir, sorted by ID.
+func GetTemplates() []*Template {
+	templates := NewBuiltInTemplates()
+
+	dir := getTemplatesDir()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"err":  err,
+			"path": dir,
+		}).Error("Could not read templates dir")
+	}
+	var stored []*Template
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != templateFileExt {
+			continue
+		}
+		t, err := readTemplateFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		if isBuiltInTemplate(t.ID) {
+			log.WithFields(log.Fields{
+				"id": t.ID,
+			}).Error("Template file conflicts with built-in template")
+			continue
+		}
+		stored = append(stored, t)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].ID < stored[j].ID
+	})
+	return append(templates, stored...)
+}
+
+// GetTemplate returns template by ID or nil if not found.
+func GetTemplate(id string) *Template {
+	for _, t := range GetTemplates() {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// NewTemplateFromMap extracts structure of the map into a template.
+// If withPlacements is true, placement rules of the map and rules
+// derived from current layout of resources are included.
+func NewTemplateFromMap(p *ProxyMap, id string, name string, withPlacements bool) *Template {
+	p.Read()
+	t := &Template{
+		Version:     TemplateVersion,
+		ID:          id,
+		Name:        name,
+		Description: p.Description,
+		Exclude:     append(make([]string, 0), p.Exclude...),
+		Styles:      append(make([]Style, 0), p.Styles...),
+		StyleRules:  append(make([]StyleRule, 0), p.StyleRules...),
+		Zones:       copyZones(p.Zones),
+		Placements:  make([]PlacementRule, 0),
+		LayerMode:   p.LayerMode,
+		Theme:       p.Theme,
+	}
+	if p.NewZone != nil {
+		newZone := *p.NewZone
+		t.NewZone = &newZone
+	}
+	if withPlacements {
+		t.Placements = append(t.Placements, p.Placements...)
+		t.Placements = append(t.Placements, p.derivePlacements()...)
+	}
+	return t
+}
+
+// Validate returns error if template cannot be stored.
+func (t *Template) Validate() error {
+	if !fileIDRegexp.MatchString(t.ID) {
+		return fmt.Errorf("invalid template id %q", t.ID)
+	}
+	if isBuiltInTemplate(t.ID) {
+		return fmt.Errorf("template id %q is reserved", t.ID)
+	}
+	for _, s := range t.Styles {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, rule := range t.StyleRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return validatePlacements(t.Placements, t.Zones)
+}
+
+// Write stores template to templates dir.
+func (t *Template) Write() error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	t.Version = TemplateVersion
+	t.BuiltIn = false
+	if err := os.MkdirAll(getTemplatesDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getTemplateFilePath(t.ID), data, 0644)
+}
+
+// ParseTemplate parses Template from Reader.
+func ParseTemplate(r io.Reader) (*Template, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := getJSONVersion(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTemplateVersion(bs, version)
+}
+
+// Apply presets map structure from template.
+// Map must not have resources yet.
+func (t *Template) Apply(p *ProxyMap) {
+	p.Exclude = append(make([]string, 0), t.Exclude...)
+	p.Styles = append(make([]Style, 0), t.Styles...)
+	p.StyleRules = append(make([]StyleRule, 0), t.StyleRules...)
+	p.Zones = copyZones(t.Zones)
+	p.Placements = append(make([]PlacementRule, 0), t.Placements...)
+	if t.NewZone != nil {
+		newZone := *t.NewZone
+		p.NewZone = &newZone
+	}
+	p.LayerMode = t.LayerMode
+	if t.Theme != "" {
+		p.Theme = t.Theme
+	}
+	p.Changed = true
+}
+
+// copyZones returns copies of given zones.
+func copyZones(zones []*Zone2D) []*Zone2D {
+	copies := make([]*Zone2D, 0)
+	for _, z := range zones {
+		zone := *z
+		copies = append(copies, &zone)
+	}
+	return copies
+}
+
+// newRectZone creates a rectangular zone with top left corner at x, y.
+func newRectZone(id int, label string, x float64, y float64, width float64, height float64) *Zone2D {
+	z := NewZone2D()
+	z.ZoneID = id
+	z.Label = label
+	z.Path = []Position{
+		{X: x, Y: y},
+		{X: x + width, Y: y},
+		{X: x + width, Y: y - height},
+		{X: x, Y: y - height},
+	}
+	return z
+}
+
+// newNewZone creates New zone at given position.
+func newNewZone(x float64, y float64, width float64, t OpenZoneType) *OpenZone2D {
+	z := NewNewZone2D()
+	z.Pos = Position2D{X: x, Y: y}
+	z.Width = width
+	z.Type = t
+	return z
+}
+
+func isBuiltInTemplate(id string) bool {
+	for _, t := range NewBuiltInTemplates() {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func readTemplateFile(path string) (*Template, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	t, err := ParseTemplate(fd)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err":  err,
+			"path": path,
+		}).Error("Could not read template JSON file")
+		return nil, err
+	}
+	logRejectedStyles(path, sanitizeTemplate(t))
+	return t, nil
+}
+
+// getTemplatesDir returns directory path for template files
+func getTemplatesDir() string {
+	return filepath.Join(config.GetDir(), TemplatesDirName)
+}
+
+// getTemplateFilePath returns path of template file with given ID
+func getTemplateFilePath(id string) string {
+	return filepath.Join(getTemplatesDir(), strings.ToLower(id)+templateFileExt)
+}
+
+// Versioning
+
+func parseTemplateVersion(bs []byte, version float64) (*Template, error) {
+	if version == 1 {
+		var data TemplateV1
+		if err := json.Unmarshal(bs, &data); err != nil {
+			return nil, err
+		}
+		return convertTemplateV1(&data)
+	}
+	return nil, fmt.Errorf("Unsupported Template JSON version %g", version)
+}
+
+func convertTemplateV1(data *TemplateV1) (*Template, error) {
+	return (*Template)(data), nil
 }
@@ -0,0 +1,328 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/filemaps/filemaps/pkg/config"
+)
+
+const (
+	// ThemeVersion defines current Theme file version.
+	ThemeVersion = 1
+	// ThemesDirName is directory under config dir for theme files.
+	ThemesDirName = "themes"
+	// DefaultThemeID is ID of theme used when map does not select one.
+	DefaultThemeID = "default"
+	// themeFileExt is file name extension for theme files.
+	themeFileExt = ".json"
+)
+
+var (
+	// fileIDRegexp defines valid IDs for themes and templates which are
+	// also used as file names
+	fileIDRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// ThemeV1 is first version of Theme struct.
+type ThemeV1 struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	// Dark tells UI to use dark background
+	Dark    bool    `json:"dark"`
+	Styles  []Style `json:"styles"`
+	BuiltIn bool    `json:"builtIn"`
+}
+
+// Theme is a named collection of styles shared between maps.
+type Theme ThemeV1
+
+// NewBuiltInThemes returns themes bundled with File Maps.
+func NewBuiltInThemes() []*Theme {
+	return []*Theme{
+		{
+			Version: ThemeVersion,
+			ID:      DefaultThemeID,
+			Name:    "Default",
+			Styles:  NewDefaultStyles(),
+			BuiltIn: true,
+		},
+		{
+			Version: ThemeVersion,
+			ID:      "dark",
+			Name:    "Dark",
+			Dark:    true,
+			Styles:  newDarkStyles(),
+			BuiltIn: true,
+		},
+		{
+			Version: ThemeVersion,
+			ID:      "colorblind",
+			Name:    "Color-blind safe",
+			Styles:  newColorBlindStyles(),
+			BuiltIn: true,
+		},
+	}
+}
+
+// GetThemes returns built-in themes followed by themes stored in
+// config dir, sorted by ID.
+func GetThemes() []*Theme {
+	themes := NewBuiltInThemes()
+
+	dir := getThemesDir()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"err":  err,
+			"path": dir,
+		}).Error("Could not read themes dir")
+	}
+	var stored []*Theme
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != themeFileExt {
+			continue
+		}
+		t, err := readThemeFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		if isBuiltInTheme(t.ID) {
+			log.WithFields(log.Fields{
+				"id": t.ID,
+			}).Error("Theme file conflicts with built-in theme")
+			continue
+		}
+		stored = append(stored, t)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].ID < stored[j].ID
+	})
+	return append(themes, stored...)
+}
+
+// GetTheme returns theme by ID or nil if not found. Only the file of
+// the theme is read, not the whole themes dir.
+func GetTheme(id string) *Theme {
+	if isBuiltInTheme(id) {
+		for _, t := range NewBuiltInThemes() {
+			if t.ID == id {
+				return t
+			}
+		}
+		return nil
+	}
+	if !fileIDRegexp.MatchString(id) {
+		return nil
+	}
+	t, err := readThemeFile(getThemeFilePath(id))
+	if err != nil || t.ID != id {
+		return nil
+	}
+	return t
+}
+
+// DeleteTheme deletes stored theme. Built-in themes cannot be deleted.
+func DeleteTheme(id string) error {
+	if isBuiltInTheme(id) {
+		return fmt.Errorf("built-in theme %q cannot be deleted", id)
+	}
+	if !fileIDRegexp.MatchString(id) {
+		return fmt.Errorf("invalid theme id %q", id)
+	}
+	return os.Remove(getThemeFilePath(id))
+}
+
+// Validate returns error if theme cannot be stored.
+func (t *Theme) Validate() error {
+	if !fileIDRegexp.MatchString(t.ID) {
+		return fmt.Errorf("invalid theme id %q", t.ID)
+	}
+	if isBuiltInTheme(t.ID) {
+		return fmt.Errorf("theme id %q is reserved", t.ID)
+	}
+	for _, s := range t.Styles {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write stores theme to themes dir.
+func (t *Theme) Write() error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	t.Version = ThemeVersion
+	t.BuiltIn = false
+	if err := os.MkdirAll(getThemesDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getThemeFilePath(t.ID), data, 0644)
+}
+
+// ParseTheme parses Theme from Reader.
+func ParseTheme(r io.Reader) (*Theme, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := getJSONVersion(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseThemeVersion(bs, version)
+}
+
+// GetTheme returns theme selected for the map, or default theme if
+// map has no theme or selected theme does not exist.
+func (p *ProxyMap) GetTheme() *Theme {
+	p.Read()
+	if p.Theme != "" {
+		if t := GetTheme(p.Theme); t != nil {
+			return t
+		}
+		log.WithFields(log.Fields{
+			"id":    p.ID,
+			"theme": p.Theme,
+		}).Error("Map theme not found, using default")
+	}
+	return GetTheme(DefaultThemeID)
+}
+
+func readThemeFile(path string) (*Theme, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	t, err := ParseTheme(fd)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err":  err,
+			"path": path,
+		}).Error("Could not read theme JSON file")
+		return nil, err
+	}
+	logRejectedStyles(path, sanitizeTheme(t))
+	return t, nil
+}
+
+// newDarkStyles returns language styles readable on dark background.
+// Dark language colors are lightened.
+func newDarkStyles() []Style {
+	overrides := map[string]string{
+		"go":   "#7fd5ea",
+		"html": "#ff7b72",
+		"md":   "#7ee787",
+		"ts":   "#79c0ff",
+	}
+	return newLanguageStyles(func(l *Language) string {
+		if c, ok := overrides[l.SClass]; ok {
+			return c
+		}
+		return lightenDarkColor(l.Color)
+	})
+}
+
+// newColorBlindStyles returns language styles using Okabe-Ito palette
+// which is distinguishable with common color vision deficiencies.
+// Languages of the same kind share a color.
+func newColorBlindStyles() []Style {
+	overrides := map[string]string{
+		"go":   "#0072b2",
+		"html": "#d55e00",
+		"md":   "#009e73",
+		"ts":   "#cc79a7",
+	}
+	kinds := map[LanguageKind]string{
+		KindProgramming: "#0072b2",
+		KindMarkup:      "#d55e00",
+		KindStylesheet:  "#cc79a7",
+		KindProse:       "#009e73",
+		KindData:        "#e69f00",
+		KindConfig:      "#56b4e9",
+		KindBuild:       "#000000",
+		KindBinary:      "#999999",
+	}
+	return newLanguageStyles(func(l *Language) string {
+		if c, ok := overrides[l.SClass]; ok {
+			return c
+		}
+		return kinds[l.Kind]
+	})
+}
+
+// lightenDarkColor mixes "#rrggbb" color with white if it is too dark
+// to be read on dark background.
+func lightenDarkColor(color string) string {
+	var r, g, b int
+	if _, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return color
+	}
+	// perceived brightness, 0-255
+	if (r*299+g*587+b*114)/1000 >= 110 {
+		return color
+	}
+	mix := func(c int) int {
+		return c + (255-c)*3/5
+	}
+	return fmt.Sprintf("#%02x%02x%02x", mix(r), mix(g), mix(b))
+}
+
+func isBuiltInTheme(id string) bool {
+	switch id {
+	case DefaultThemeID, "dark", "colorblind":
+		return true
+	}
+	return false
+}
+
+// getThemesDir returns directory path for theme files
+func getThemesDir() string {
+	return filepath.Join(config.GetDir(), ThemesDirName)
+}
+
+// getThemeFilePath returns path of theme file with given ID
+func getThemeFilePath(id string) string {
+	return filepath.Join(getThemesDir(), strings.ToLower(id)+themeFileExt)
+}
+
+// Versioning
+
+func parseThemeVersion(bs []byte, version float64) (*Theme, error) {
+	if version == 1 {
+		var data ThemeV1
+		if err := json.Unmarshal(bs, &data); err != nil {
+			return nil, err
+		}
+		return convertThemeV1(&data)
+	}
+	return nil, fmt.Errorf("Unsupported Theme JSON version %g", version)
+}
+
+func convertThemeV1(data *ThemeV1) (*Theme, error) {
+	return (*Theme)(data), nil
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// setTestConfigDir points config dir to a temporary directory.
+// Returned function restores the environment.
+func setTestConfigDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "filemaps-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := []string{"XDG_CONFIG_HOME", "HOME", "LocalAppData"}
+	old := make(map[string]string)
+	for _, v := range vars {
+		old[v] = os.Getenv(v)
+		os.Setenv(v, dir)
+	}
+	return func() {
+		for _, v := range vars {
+			os.Setenv(v, old[v])
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBuiltInThemes(t *testing.T) {
+	defer setTestConfigDir(t)()
+
+	for _, id := range []string{DefaultThemeID, "dark", "colorblind"} {
+		theme := GetTheme(id)
+		if theme == nil || theme.ID != id || !theme.BuiltIn {
+			t.Errorf("Expected built-in theme %q, got %+v", id, theme)
+		}
+		if err := DeleteTheme(id); err == nil {
+			t.Errorf("Expected error deleting built-in theme %q", id)
+		}
+	}
+	for _, id := range []string{"missing", "../config", ""} {
+		if theme := GetTheme(id); theme != nil {
+			t.Errorf("Expected no theme for %q, got %+v", id, theme)
+		}
+	}
+	if n := len(GetThemes()); n != 3 {
+		t.Errorf("Expected 3 themes, got %d", n)
+	}
+
+	pm := getTestProxyMap()
+	if theme := pm.GetTheme(); theme.ID != DefaultThemeID {
+		t.Errorf("Expected default theme for map, got %q", theme.ID)
+	}
+	pm.Theme = "missing"
+	if theme := pm.GetTheme(); theme.ID != DefaultThemeID {
+		t.Errorf("Expected default theme for missing theme, got %q", theme.ID)
+	}
+}
+
+func TestImportExportTheme(t *testing.T) {
+	defer setTestConfigDir(t)()
+
+	data := `{"version":1,"id":"ocean","name":"Ocean","dark":true,"builtIn":true,
+		"styles":[{"sClass":"go","rules":{"color":"#0000ff"}}]}`
+	theme, err := ParseTheme(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := theme.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := GetTheme("ocean")
+	if imported == nil || imported.Name != "Ocean" || !imported.Dark || imported.BuiltIn {
+		t.Fatalf("Unexpected imported theme %+v", imported)
+	}
+	if len(imported.Styles) != 1 || imported.Styles[0].Rules["color"] != "#0000ff" {
+		t.Errorf("Unexpected styles %+v", imported.Styles)
+	}
+	if themes := GetThemes(); len(themes) != 4 || themes[3].ID != "ocean" {
+		t.Errorf("Expected stored theme after built-in themes, got %d themes", len(themes))
+	}
+
+	// exported JSON can be imported again
+	exported, err := json.Marshal(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := ParseTheme(bytes.NewBuffer(exported)); err != nil || again.ID != "ocean" {
+		t.Errorf("Could not parse exported theme: %v", err)
+	}
+
+	pm := getTestProxyMap()
+	pm.Theme = "ocean"
+	if s := pm.GetClassStyle("go"); s == nil || s.Rules["color"] != "#0000ff" {
+		t.Errorf("Expected class style from map theme, got %+v", s)
+	}
+
+	if err := DeleteTheme("ocean"); err != nil {
+		t.Fatal(err)
+	}
+	if GetTheme("ocean") != nil {
+		t.Error("Expected theme to be deleted")
+	}
+
+	var invalid = []string{
+		`{"version":1,"id":"dark","name":"Dark"}`,
+		`{"version":1,"id":"Bad ID","name":"Bad"}`,
+	}
+	for _, data := range invalid {
+		theme, err := ParseTheme(bytes.NewBufferString(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := theme.Write(); err == nil {
+			t.Errorf("Expected error writing theme %s", data)
+		}
+	}
+}
+
+func TestLegacyStyles(t *testing.T) {
+	defer setTestConfigDir(t)()
+
+	// map saved before themes, with a changed md color
+	data := `{"version":1,"title":"Legacy","resources":[],"styles":[
+		{"sClass":"go","rules":{"color":"#375eab"}},
+		{"sClass":"html","rules":{"color":"#ff0000"}},
+		{"sClass":"md","rules":{"color":"#123456"}},
+		{"sClass":"ts","rules":{"color":"#0000ff"}}]}`
+	pm := getTestProxyMap()
+	if err := pm.ParseJSON(bytes.NewBufferString(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(pm.Styles) != 1 || pm.Styles[0].SClass != "md" {
+		t.Fatalf("Expected only changed md style to be kept, got %+v", pm.Styles)
+	}
+
+	pm.Theme = "dark"
+	theme := GetTheme("dark")
+	for _, sclass := range []string{"go", "html", "ts"} {
+		var themeColor string
+		for _, s := range theme.Styles {
+			if s.SClass == sclass {
+				themeColor = s.Rules["color"]
+			}
+		}
+		s := pm.GetClassStyle(sclass)
+		if s == nil || s.Rules["color"] != themeColor {
+			t.Errorf("Expected %s to have theme color %q, got %+v", sclass, themeColor, s)
+		}
+	}
+	if s := pm.GetClassStyle("md"); s == nil || s.Rules["color"] != "#123456" {
+		t.Errorf("Expected md to keep map color, got %+v", s)
+	}
+}
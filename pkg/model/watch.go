@@ -0,0 +1,277 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"sync"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/config"
+	"github.com/filemaps/filemaps/pkg/ignore"
+	"github.com/filemaps/filemaps/pkg/scanner"
+	"github.com/filemaps/filemaps/pkg/watcher"
+)
+
+const (
+	// watchPollInterval is interval of polling where native
+	// file watching is not available.
+	watchPollInterval = 2 * time.Second
+	// watchBatchDelay is how long events are collected before
+	// they are applied, so a burst of changes writes the map once.
+	watchBatchDelay = 300 * time.Millisecond
+)
+
+var (
+	mapWatchersMu sync.Mutex
+	mapWatchers   = make(map[int]*mapWatcher) // map ID -> watcher
+)
+
+// mapWatcher applies file changes under map base to the map.
+type mapWatcher struct {
+	pm   *ProxyMap
+	w    watcher.Watcher
+	stop chan struct{}
+}
+
+// WatchResult tells what a batch of file changes changed.
+type WatchResult struct {
+	// Resources are added resources
+	Resources []*Resource
+	// Missing are resources whose files were removed
+	Missing []ResourceID
+	// Found are missing resources whose files appeared again
+	Found []ResourceID
+}
+
+// StartWatchers starts watching maps having watch mode enabled.
+func StartWatchers() {
+	mm := GetMapManager()
+	for _, mi := range mm.MapInfos {
+		pm := mm.GetProxyMap(mi.ID)
+		pm.Lock()
+		if err := pm.Read(); err == nil && pm.Watch {
+			pm.startWatch()
+		}
+		pm.Unlock()
+	}
+}
+
+// SetWatch enables or disables watch mode of the map.
+// Map must be locked by the caller.
+func (p *ProxyMap) SetWatch(enabled bool) error {
+	p.Read()
+	if enabled {
+		if err := p.startWatch(); err != nil {
+			return err
+		}
+	} else {
+		p.stopWatch()
+	}
+	p.Watch = enabled
+	p.Changed = true
+	return nil
+}
+
+// IsWatching tells if files of the map are being watched.
+func (p *ProxyMap) IsWatching() bool {
+	mapWatchersMu.Lock()
+	defer mapWatchersMu.Unlock()
+	return mapWatchers[p.ID] != nil
+}
+
+// restartWatch restarts watcher after base or exclude
+// patterns of the map changed.
+func (p *ProxyMap) restartWatch() {
+	if p.IsWatching() {
+		p.stopWatch()
+		p.startWatch()
+	}
+}
+
+func (p *ProxyMap) startWatch() error {
+	mapWatchersMu.Lock()
+	defer mapWatchersMu.Unlock()
+	if mapWatchers[p.ID] != nil {
+		return nil
+	}
+
+	if err := config.CheckPath(p.Base); err != nil {
+		return err
+	}
+
+	// exclude patterns are checked again when events are applied,
+	// this only avoids watching excluded directories
+	w, err := watcher.New(p.Base, watchSkip(p.Exclude), watchPollInterval)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id":   p.ID,
+			"base": p.Base,
+			"err":  err,
+		}).Error("Could not watch map")
+		return err
+	}
+	mw := &mapWatcher{
+		pm:   p,
+		w:    w,
+		stop: make(chan struct{}),
+	}
+	mapWatchers[p.ID] = mw
+	go mw.run()
+
+	log.WithFields(log.Fields{
+		"id":   p.ID,
+		"base": p.Base,
+	}).Info("Watching map")
+	return nil
+}
+
+// watchSkip returns function skipping .git and excluded paths.
+func watchSkip(exclude []string) watcher.SkipFunc {
+	m := ignore.NewMatcher(exclude)
+	return func(path string, isDir bool) bool {
+		return (isDir && path == ".git") || m.Excluded(path, isDir)
+	}
+}
+
+// stopWatch stops watcher of the map. It does not wait for a batch
+// being applied, since the caller holds the map lock.
+func (p *ProxyMap) stopWatch() {
+	mapWatchersMu.Lock()
+	mw := mapWatchers[p.ID]
+	delete(mapWatchers, p.ID)
+	mapWatchersMu.Unlock()
+	if mw == nil {
+		return
+	}
+	close(mw.stop)
+	mw.w.Close()
+
+	log.WithFields(log.Fields{
+		"id": p.ID,
+	}).Info("Stopped watching map")
+}
+
+func (mw *mapWatcher) run() {
+	var batch []watcher.Event
+	var timer <-chan time.Time
+	events := mw.w.Events()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			batch = append(batch, e)
+			if timer == nil {
+				timer = time.After(watchBatchDelay)
+			}
+		case <-timer:
+			mw.apply(batch)
+			batch = nil
+			timer = nil
+		case <-mw.stop:
+			return
+		}
+	}
+}
+
+// apply applies a batch of events to the map and writes it.
+func (mw *mapWatcher) apply(events []watcher.Event) {
+	mw.pm.Lock()
+	defer mw.pm.Unlock()
+	select {
+	case <-mw.stop:
+		// stopped while waiting for the lock
+		return
+	default:
+	}
+
+	result := mw.pm.ApplyWatchEvents(events)
+	if len(result.Resources) == 0 && len(result.Missing) == 0 && len(result.Found) == 0 {
+		return
+	}
+	if err := mw.pm.Write(); err != nil {
+		log.WithFields(log.Fields{
+			"id":  mw.pm.ID,
+			"err": err,
+		}).Error("Could not write map after file changes")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":      mw.pm.ID,
+		"added":   len(result.Resources),
+		"missing": len(result.Missing),
+		"found":   len(result.Found),
+	}).Info("Applied file changes")
+}
+
+// ApplyWatchEvents adds created files to the new zone of the map and
+// flags resources of removed files as missing. New files a scan with
+// the map's options would not find are ignored: excluded by ignore files
+// or exclude patterns, filtered out, or not listed by git.
+func (p *ProxyMap) ApplyWatchEvents(events []watcher.Event) *WatchResult {
+	p.Read()
+	var matcher *scanner.Matcher
+	byPath := make(map[string]*Resource)
+	for _, rsrc := range p.Resources {
+		byPath[rsrc.Path] = rsrc
+	}
+
+	result := &WatchResult{}
+	for _, e := range events {
+		switch e.Op {
+		case watcher.Create:
+			if rsrc := byPath[e.Path]; rsrc != nil {
+				if rsrc.Missing {
+					rsrc.Missing = false
+					result.Found = append(result.Found, rsrc.ResourceID)
+					p.Changed = true
+				}
+				continue
+			}
+			if matcher == nil {
+				matcher = p.scanMatcher()
+			}
+			if matcher.Exclusion(e.Path) != nil {
+				continue
+			}
+			rsrc := &Resource{
+				Type: ResourceFile,
+				Path: e.Path,
+			}
+			p.AddResource(rsrc)
+			p.AssignResourceStyle(rsrc)
+			byPath[rsrc.Path] = rsrc
+			result.Resources = append(result.Resources, rsrc)
+		case watcher.Remove:
+			// removed directory flags everything under it
+			for _, rsrc := range p.Resources {
+				if rsrc.Missing || (rsrc.Path != e.Path && !isUnderDir(rsrc.Path, e.Path)) {
+					continue
+				}
+				rsrc.Missing = true
+				result.Missing = append(result.Missing, rsrc.ResourceID)
+				p.Changed = true
+			}
+		}
+	}
+	p.AssignPositions(result.Resources)
+	p.AssignLayers(result.Resources)
+	return result
+}
+
+// scanMatcher returns matcher telling if files under base would be
+// found by a scan with the map's options.
+func (p *ProxyMap) scanMatcher() *scanner.Matcher {
+	return p.newScanMatcher(p.GetScanOptions())
+}
+
+// newScanMatcher returns matcher for scans with given options.
+func (p *ProxyMap) newScanMatcher(opts ScanOptions) *scanner.Matcher {
+	return scanner.NewMatcher(p.Base, opts.scannerOptions())
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/filemaps/filemaps/pkg/scanner"
+	"github.com/filemaps/filemaps/pkg/watcher"
+)
+
+func TestApplyWatchEvents(t *testing.T) {
+	pm := getTestScanMap(t, []string{"new.go", "debug.log"})
+	defer os.RemoveAll(pm.Base)
+	pm.Exclude = []string{"*.log"}
+	for _, path := range []string{"a.go", "lib/b.go", "lib/c.go"} {
+		pm.AddResource(&Resource{Path: filepath.FromSlash(path)})
+	}
+
+	result := pm.ApplyWatchEvents([]watcher.Event{
+		{Path: "new.go", Op: watcher.Create},
+		{Path: "debug.log", Op: watcher.Create},
+		{Path: "a.go", Op: watcher.Create},
+		{Path: "lib", Op: watcher.Remove},
+	})
+	if len(result.Resources) != 1 || result.Resources[0].Path != "new.go" {
+		t.Errorf("added %v, expected new.go", result.Resources)
+	}
+	if len(result.Missing) != 2 {
+		t.Errorf("%d missing, expected 2", len(result.Missing))
+	}
+	if pm.GetResourceByPath("a.go").Missing {
+		t.Error("a.go flagged missing")
+	}
+	if !pm.GetResourceByPath(filepath.Join("lib", "b.go")).Missing {
+		t.Error("lib/b.go not flagged missing")
+	}
+
+	result = pm.ApplyWatchEvents([]watcher.Event{
+		{Path: filepath.Join("lib", "b.go"), Op: watcher.Create},
+	})
+	if len(result.Found) != 1 || len(result.Resources) != 0 {
+		t.Errorf("found %v, added %v, expected lib/b.go found", result.Found, result.Resources)
+	}
+	if pm.GetResourceByPath(filepath.Join("lib", "b.go")).Missing {
+		t.Error("lib/b.go still missing")
+	}
+}
+
+func TestApplyWatchEventsIgnored(t *testing.T) {
+	files := []string{
+		".gitignore",
+		"src/main.go",
+		"node_modules/lib/index.js",
+		"build/out.o",
+		"deep/a/b/c.go",
+		"tmp.swp",
+	}
+	pm := getTestScanMap(t, files)
+	defer os.RemoveAll(pm.Base)
+	ioutil.WriteFile(filepath.Join(pm.Base, ".gitignore"), []byte("node_modules/\n/build\n"), 0600)
+	ioutil.WriteFile(filepath.Join(pm.Base, ".filemapsignore"), []byte("*.swp\n"), 0600)
+	pm.IgnoreFiles = true
+
+	var events []watcher.Event
+	for _, path := range files[1:] {
+		events = append(events, watcher.Event{Path: filepath.FromSlash(path), Op: watcher.Create})
+	}
+	result := pm.ApplyWatchEvents(events)
+	var added []string
+	for _, rsrc := range result.Resources {
+		added = append(added, filepath.ToSlash(rsrc.Path))
+	}
+	if len(added) != 2 || added[0] != "src/main.go" || added[1] != "deep/a/b/c.go" {
+		t.Errorf("added %v, expected src/main.go and deep/a/b/c.go", added)
+	}
+}
+
+func TestApplyWatchEventsGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	pm := getTestScanMap(t, []string{"tracked.go", "untracked.go", "ignored.log"})
+	defer os.RemoveAll(pm.Base)
+	ioutil.WriteFile(filepath.Join(pm.Base, ".gitignore"), []byte("*.log\n"), 0600)
+	for _, args := range [][]string{{"init", "-q"}, {"add", "tracked.go"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = pm.Base
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatal(err, string(out))
+		}
+	}
+	pm.ScanSource = scanner.SourceGit
+
+	events := []watcher.Event{
+		{Path: "tracked.go", Op: watcher.Create},
+		{Path: "untracked.go", Op: watcher.Create},
+		{Path: "ignored.log", Op: watcher.Create},
+	}
+	result := pm.ApplyWatchEvents(events)
+	if len(result.Resources) != 1 || result.Resources[0].Path != "tracked.go" {
+		t.Errorf("added %v, expected tracked.go", result.Resources)
+	}
+
+	pm.ScanUntracked = true
+	result = pm.ApplyWatchEvents(events)
+	if len(result.Resources) != 1 || result.Resources[0].Path != "untracked.go" {
+		t.Errorf("added %v, expected untracked.go", result.Resources)
+	}
+}
@@ -5,6 +5,10 @@
 
 package model
 
+import (
+	"math"
+)
+
 // Zone2DV1 is the first version from Zone2D struct
 type Zone2DV1 struct {
 	ZoneID  int               `json:"id"`
@@ -24,3 +28,26 @@ func NewZone2D() *Zone2D {
 	}
 	return z
 }
+
+// bounds returns bounding box of the zone path.
+// ok is false if zone has no path.
+func (z *Zone2D) bounds() (minX float64, minY float64, maxX float64, maxY float64, ok bool) {
+	if len(z.Path) == 0 {
+		return 0, 0, 0, 0, false
+	}
+	minX, minY = z.Path[0].X, z.Path[0].Y
+	maxX, maxY = minX, minY
+	for _, pos := range z.Path[1:] {
+		minX = math.Min(minX, pos.X)
+		minY = math.Min(minY, pos.Y)
+		maxX = math.Max(maxX, pos.X)
+		maxY = math.Max(maxY, pos.Y)
+	}
+	return minX, minY, maxX, maxY, true
+}
+
+// contains tells if position is inside bounding box of the zone.
+func (z *Zone2D) contains(pos Position) bool {
+	minX, minY, maxX, maxY, ok := z.bounds()
+	return ok && pos.X >= minX && pos.X <= maxX && pos.Y >= minY && pos.Y <= maxY
+}
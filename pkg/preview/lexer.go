@@ -0,0 +1,190 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package preview
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token classes
+const (
+	ClassText        = "text"
+	ClassKeyword     = "keyword"
+	ClassName        = "name"
+	ClassString      = "string"
+	ClassNumber      = "number"
+	ClassComment     = "comment"
+	ClassPunctuation = "punctuation"
+)
+
+// Token is a piece of a line with a class for highlighting.
+type Token struct {
+	Class string `json:"class"`
+	Text  string `json:"text"`
+}
+
+// lexer splits lines to tokens. Block comments and multiline strings
+// are continued on following lines.
+type lexer struct {
+	syn *syntax
+	// end is delimiter ending open block comment or string
+	end   string
+	class string
+	raw   bool
+
+	tokens []Token
+}
+
+func newLexer(lang string) *lexer {
+	return &lexer{syn: getSyntax(lang)}
+}
+
+// line returns tokens of a line.
+func (l *lexer) line(s string) []Token {
+	l.tokens = nil
+	for s != "" {
+		if l.end != "" {
+			s = l.continued(s)
+			continue
+		}
+		// block comments first, "--[[" of Lua starts with "--"
+		if n := l.openComment(s); n > 0 {
+			s = s[n:]
+			continue
+		}
+		if hasPrefix(s, l.syn.lineComments) != "" {
+			l.emit(ClassComment, s)
+			break
+		}
+		if n := l.openString(s); n > 0 {
+			s = s[n:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		n := size
+		class := ClassPunctuation
+		switch {
+		case unicode.IsSpace(r):
+			n = scan(s, unicode.IsSpace)
+			class = ClassText
+		case unicode.IsDigit(r):
+			n = scan(s, func(r rune) bool {
+				return r == '.' || isWordRune(r)
+			})
+			class = ClassNumber
+		case isWordRune(r):
+			n = scan(s, isWordRune)
+			class = l.wordClass(s[:n])
+		}
+		l.emit(class, s[:n])
+		s = s[n:]
+	}
+	return l.tokens
+}
+
+// openComment handles start of a block comment. Returns number of
+// bytes consumed, 0 if s does not start a block comment.
+func (l *lexer) openComment(s string) int {
+	for _, bc := range l.syn.blockComments {
+		if strings.HasPrefix(s, bc[0]) {
+			l.end, l.class, l.raw = bc[1], ClassComment, true
+			l.emit(ClassComment, bc[0])
+			return len(bc[0])
+		}
+	}
+	return 0
+}
+
+// openString handles start of a string. Returns number of bytes
+// consumed, 0 if s does not start a string.
+func (l *lexer) openString(s string) int {
+	q := hasPrefix(s, l.syn.quotes)
+	if q == "" {
+		return 0
+	}
+	raw := l.syn.raw[q]
+	if !l.syn.multiline[q] && findEnd(s[len(q):], q, raw) < 0 {
+		// unterminated quote, like an apostrophe in text
+		return 0
+	}
+	l.end, l.class, l.raw = q, ClassString, raw
+	l.emit(ClassString, q)
+	return len(q)
+}
+
+// continued consumes s until end of open block comment or string.
+func (l *lexer) continued(s string) string {
+	i := findEnd(s, l.end, l.raw)
+	if i < 0 {
+		l.emit(l.class, s)
+		return ""
+	}
+	i += len(l.end)
+	l.emit(l.class, s[:i])
+	l.end = ""
+	return s[i:]
+}
+
+func (l *lexer) wordClass(word string) string {
+	if l.syn.caseless {
+		word = strings.ToLower(word)
+	}
+	if l.syn.keywords[word] {
+		return ClassKeyword
+	}
+	return ClassName
+}
+
+// emit adds token, merging it with previous token of the same class.
+func (l *lexer) emit(class string, text string) {
+	if n := len(l.tokens); n > 0 && l.tokens[n-1].Class == class {
+		l.tokens[n-1].Text += text
+		return
+	}
+	l.tokens = append(l.tokens, Token{Class: class, Text: text})
+}
+
+// findEnd returns index of end delimiter in s, skipping backslash
+// escapes unless raw. Returns -1 if not found.
+func findEnd(s string, end string, raw bool) int {
+	for i := 0; i < len(s); i++ {
+		if !raw && s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], end) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasPrefix returns the first prefix s starts with, or empty string.
+func hasPrefix(s string, prefixes []string) string {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// scan returns length of prefix of s consisting of runes accepted by f.
+func scan(s string, f func(rune) bool) int {
+	for i, r := range s {
+		if !f(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
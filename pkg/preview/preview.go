@@ -0,0 +1,278 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// Package preview generates previews of files: first lines of text
+// files split to tokens for highlighting, and thumbnails of images.
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultLines is number of lines in text previews by default.
+	DefaultLines = 50
+	// MaxLines is maximum number of lines in text previews.
+	MaxLines = 500
+	// DefaultThumbnailSize is maximum width and height of thumbnails
+	// by default.
+	DefaultThumbnailSize = 256
+	// MaxThumbnailSize is maximum width and height of thumbnails.
+	MaxThumbnailSize = 1024
+
+	// maxTextBytes is number of bytes read for text previews.
+	maxTextBytes = 256 * 1024
+	// maxLineLength is number of bytes of a line kept in previews,
+	// minified files can have very long lines.
+	maxLineLength = 1000
+	// maxImageBytes is size of largest image thumbnailed.
+	maxImageBytes = 32 * 1024 * 1024
+	// maxImagePixels is number of pixels in largest image thumbnailed,
+	// so small compressed files cannot use lots of memory.
+	maxImagePixels = 64 * 1024 * 1024
+	// sniffLength is number of bytes read to detect file type.
+	sniffLength = 8000
+	// cacheSize is number of previews cached.
+	cacheSize = 128
+)
+
+// Preview types
+const (
+	TypeText   = "text"
+	TypeImage  = "image"
+	TypeBinary = "binary"
+)
+
+// Options for Generate.
+type Options struct {
+	// Lines is number of lines of text previews
+	Lines int
+	// ThumbnailSize is maximum width and height of image thumbnails
+	ThumbnailSize int
+	// Language is style class of model.Language of the file,
+	// used for choosing a lexer
+	Language string
+}
+
+// Preview of a file.
+type Preview struct {
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Lines are tokens of first lines of text files
+	Lines [][]Token `json:"lines"`
+	// Truncated tells if text file has more lines than in Lines
+	Truncated bool `json:"truncated"`
+	// Thumbnail is PNG image, base64 encoded in JSON
+	Thumbnail []byte `json:"thumbnail"`
+	// Width and Height are dimensions of original image
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// TooLarge is set for images too large to be thumbnailed
+	TooLarge bool `json:"tooLarge"`
+}
+
+// cacheKey identifies a preview. Changed files get new keys.
+type cacheKey struct {
+	path     string
+	size     int64
+	modified time.Time
+	opts     Options
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	preview *Preview
+}
+
+var (
+	cacheMu sync.Mutex
+	// cacheList has most recently used entries first
+	cacheList = list.New()
+	cacheIdx  = make(map[cacheKey]*list.Element)
+)
+
+// Generate returns preview of file. Previews are cached by path, size
+// and modification time, and must not be modified by the caller.
+func Generate(path string, opts Options) (*Preview, error) {
+	opts = normalize(opts)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey{
+		path:     path,
+		size:     info.Size(),
+		modified: info.ModTime(),
+		opts:     opts,
+	}
+	if p := cached(key); p != nil {
+		return p, nil
+	}
+
+	p, err := generate(path, info, opts)
+	if err != nil {
+		return nil, err
+	}
+	store(key, p)
+	return p, nil
+}
+
+// normalize applies defaults and limits to options.
+func normalize(opts Options) Options {
+	if opts.Lines <= 0 {
+		opts.Lines = DefaultLines
+	} else if opts.Lines > MaxLines {
+		opts.Lines = MaxLines
+	}
+	if opts.ThumbnailSize <= 0 {
+		opts.ThumbnailSize = DefaultThumbnailSize
+	} else if opts.ThumbnailSize > MaxThumbnailSize {
+		opts.ThumbnailSize = MaxThumbnailSize
+	}
+	return opts
+}
+
+func generate(path string, info os.FileInfo, opts Options) (*Preview, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	p := &Preview{
+		Type:     TypeBinary,
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+	}
+	br := bufio.NewReaderSize(fh, sniffLength)
+	head, err := peek(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(http.DetectContentType(head), "image/") {
+		// dimensions can be after metadata longer than head,
+		// so config is read from the file
+		cfg, _, cfgErr := image.DecodeConfig(br)
+		if _, err := fh.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		br.Reset(fh)
+		if cfgErr == nil {
+			p.Type = TypeImage
+			p.Width, p.Height = cfg.Width, cfg.Height
+			err = p.readImage(br, info.Size(), opts)
+			return p, err
+		}
+		if head, err = peek(br); err != nil {
+			return nil, err
+		}
+	}
+	if bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(trimIncomplete(head)) {
+		return p, nil
+	}
+	p.Type = TypeText
+	p.readText(io.LimitReader(br, maxTextBytes), opts)
+	if info.Size() > maxTextBytes {
+		p.Truncated = true
+	}
+	return p, nil
+}
+
+// peek returns beginning of file for detecting its type.
+func peek(br *bufio.Reader) ([]byte, error) {
+	head, err := br.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	return head, nil
+}
+
+// readImage sets thumbnail of image, unless the image is too large.
+func (p *Preview) readImage(r io.Reader, size int64, opts Options) error {
+	if size > maxImageBytes || int64(p.Width)*int64(p.Height) > maxImagePixels {
+		p.TooLarge = true
+		return nil
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return err
+	}
+	p.Thumbnail, err = thumbnail(img, opts.ThumbnailSize)
+	return err
+}
+
+// readText sets tokens of first lines of text.
+func (p *Preview) readText(r io.Reader, opts Options) {
+	l := newLexer(opts.Language)
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxTextBytes)
+	p.Lines = make([][]Token, 0)
+	for s.Scan() {
+		if len(p.Lines) == opts.Lines {
+			p.Truncated = true
+			break
+		}
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(line) > maxLineLength {
+			line = string(trimIncomplete([]byte(line[:maxLineLength])))
+		}
+		p.Lines = append(p.Lines, l.line(line))
+	}
+	if s.Err() != nil {
+		// line longer than what is read
+		p.Truncated = true
+	}
+}
+
+// trimIncomplete removes incomplete UTF-8 sequence from end of b.
+func trimIncomplete(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func cached(key cacheKey) *Preview {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	e, ok := cacheIdx[key]
+	if !ok {
+		return nil
+	}
+	cacheList.MoveToFront(e)
+	return e.Value.(*cacheEntry).preview
+}
+
+// store caches preview, evicting least recently used previews.
+func store(key cacheKey, p *Preview) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if _, ok := cacheIdx[key]; ok {
+		return
+	}
+	cacheIdx[key] = cacheList.PushFront(&cacheEntry{key: key, preview: p})
+	for cacheList.Len() > cacheSize {
+		e := cacheList.Back()
+		cacheList.Remove(e)
+		delete(cacheIdx, e.Value.(*cacheEntry).key)
+	}
+}
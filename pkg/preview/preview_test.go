@@ -0,0 +1,147 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package preview
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	l := newLexer("go")
+	tests := []struct {
+		line     string
+		expected []Token
+	}{
+		{`func f() string { // doc`, []Token{
+			{ClassKeyword, "func"}, {ClassText, " "}, {ClassName, "f"},
+			{ClassPunctuation, "()"}, {ClassText, " "}, {ClassName, "string"},
+			{ClassText, " "}, {ClassPunctuation, "{"}, {ClassText, " "},
+			{ClassComment, "// doc"},
+		}},
+		{`	return "a\"b" + 1.5 /* x`, []Token{
+			{ClassText, "\t"}, {ClassKeyword, "return"}, {ClassText, " "},
+			{ClassString, `"a\"b"`}, {ClassText, " "}, {ClassPunctuation, "+"},
+			{ClassText, " "}, {ClassNumber, "1.5"}, {ClassText, " "},
+			{ClassComment, "/* x"},
+		}},
+		{`y */ s := ` + "`raw", []Token{
+			{ClassComment, "y */"}, {ClassText, " "}, {ClassName, "s"},
+			{ClassText, " "}, {ClassPunctuation, ":="}, {ClassText, " "},
+			{ClassString, "`raw"},
+		}},
+		{"\\n` 'c'", []Token{
+			{ClassString, "\\n`"}, {ClassText, " "}, {ClassString, "'c'"},
+		}},
+	}
+	for _, test := range tests {
+		tokens := l.line(test.line)
+		if len(tokens) != len(test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.line, test.expected, tokens)
+			continue
+		}
+		for i := range tokens {
+			if tokens[i] != test.expected[i] {
+				t.Errorf("%q: expected %v, got %v", test.line, test.expected[i], tokens[i])
+			}
+		}
+	}
+
+	// unterminated quote in text is punctuation
+	tokens := newLexer("md").line("don't")
+	if len(tokens) != 3 || tokens[1] != (Token{ClassPunctuation, "'"}) {
+		t.Errorf("Unexpected tokens %v", tokens)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filemaps-preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	text := filepath.Join(dir, "a.py")
+	ioutil.WriteFile(text, []byte("import os\n# comment\nx = 1\n"), 0600)
+	p, err := Generate(text, Options{Lines: 2, Language: "py"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != TypeText || len(p.Lines) != 2 || !p.Truncated {
+		t.Errorf("Unexpected text preview %+v", p)
+	} else if p.Lines[0][0].Class != ClassKeyword || p.Lines[1][0].Class != ClassComment {
+		t.Errorf("Unexpected tokens %v", p.Lines)
+	}
+	if cached, _ := Generate(text, Options{Lines: 2, Language: "py"}); cached != p {
+		t.Error("Preview not cached")
+	}
+
+	bin := filepath.Join(dir, "a.bin")
+	ioutil.WriteFile(bin, []byte{1, 0, 2}, 0600)
+	if p, err := Generate(bin, Options{}); err != nil || p.Type != TypeBinary {
+		t.Errorf("Unexpected binary preview %+v %v", p, err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	imgPath := filepath.Join(dir, "a.png")
+	ioutil.WriteFile(imgPath, buf.Bytes(), 0600)
+
+	p, err = Generate(imgPath, Options{ThumbnailSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != TypeImage || p.Width != 400 || p.Height != 200 {
+		t.Fatalf("Unexpected image preview %+v", p)
+	}
+	thumb, err := png.Decode(bytes.NewReader(p.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Errorf("Expected 100x50 thumbnail, got %v", b)
+	}
+	if r, g, _, a := thumb.At(50, 25).RGBA(); r != 0xffff || g != 0 || a != 0xffff {
+		t.Errorf("Unexpected thumbnail color %v", thumb.At(50, 25))
+	}
+
+	// JPEG with metadata longer than sniffed head
+	buf.Reset()
+	jpeg.Encode(&buf, img, nil)
+	jpg := buf.Bytes()
+	app := make([]byte, 4+20000)
+	app[0], app[1] = 0xff, 0xe1
+	app[2], app[3] = byte((len(app)-2)>>8), byte(len(app)-2)
+	jpgPath := filepath.Join(dir, "a.jpg")
+	ioutil.WriteFile(jpgPath, append(append(jpg[:2:2], app...), jpg[2:]...), 0600)
+	p, err = Generate(jpgPath, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != TypeImage || p.Width != 400 || p.Height != 200 || len(p.Thumbnail) == 0 {
+		t.Errorf("Unexpected JPEG preview %+v", p)
+	}
+
+	// text detected as image is previewed as text
+	bmp := filepath.Join(dir, "bm.txt")
+	ioutil.WriteFile(bmp, []byte("BM is not a bitmap\n"), 0600)
+	if p, err := Generate(bmp, Options{}); err != nil || p.Type != TypeText || len(p.Lines) != 1 {
+		t.Errorf("Unexpected text preview %+v %v", p, err)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package preview
+
+import (
+	"strings"
+)
+
+// syntax describes lexical rules of a language family.
+type syntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	// quotes are string delimiters, longest first
+	quotes []string
+	// multiline quotes may span lines
+	multiline map[string]bool
+	// raw quotes do not have backslash escapes
+	raw      map[string]bool
+	keywords map[string]bool
+	// caseless keywords are matched in lower case
+	caseless bool
+}
+
+// words returns set of space separated words.
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	cKeywords = "auto break case char const continue default do double else enum extern float for goto if inline int long register return short signed sizeof static struct switch typedef union unsigned void volatile while NULL true false bool"
+
+	cSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`},
+		keywords:      words(cKeywords),
+	}
+	cppSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`},
+		keywords:      words(cKeywords + " class namespace template typename public private protected virtual override final new delete this throw try catch using nullptr constexpr auto operator friend explicit mutable noexcept static_cast dynamic_cast reinterpret_cast const_cast"),
+	}
+	goSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{"`", `"`, `'`},
+		multiline:     words("`"),
+		raw:           words("`"),
+		keywords:      words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota"),
+	}
+	javaSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"""`, `"`, `'`},
+		multiline:     words(`"""`),
+		keywords:      words("abstract assert boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long native new package private protected public return short static super switch synchronized this throw throws try void volatile while null true false var val fun object when override data sealed companion internal open lateinit suspend def trait"),
+	}
+	csSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`},
+		keywords:      words("abstract as base bool break byte case catch char class const continue decimal default delegate do double else enum event explicit extern false finally float for foreach if implicit in int interface internal is lock long namespace new null object operator out override params private protected public readonly ref return sealed short sizeof static string struct switch this throw true try typeof uint ulong using var virtual void while async await"),
+	}
+	jsSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{"`", `"`, `'`},
+		multiline:     words("`"),
+		keywords:      words("async await break case catch class const continue debugger default delete do else export extends false finally for from function if import in instanceof let new null of return static super switch this throw true try typeof undefined var void while yield interface type enum implements declare readonly private public protected abstract as"),
+	}
+	rustSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`},
+		keywords:      words("as async await break const continue crate dyn else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while"),
+	}
+	swiftSyntax = &syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"""`, `"`},
+		multiline:     words(`"""`),
+		keywords:      words("associatedtype class deinit enum extension func import init inout internal let operator private protocol public static struct subscript typealias var break case continue default defer do else fallthrough for guard if in repeat return switch where while as catch false is nil rethrows super self Self throw throws true try"),
+	}
+	pySyntax = &syntax{
+		lineComments: []string{"#"},
+		quotes:       []string{`"""`, `'''`, `"`, `'`},
+		multiline:    words(`""" '''`),
+		keywords:     words("and as assert async await break class continue def del elif else except False finally for from global if import in is lambda None nonlocal not or pass raise return True try while with yield"),
+	}
+	rbSyntax = &syntax{
+		lineComments:  []string{"#"},
+		blockComments: [][2]string{{"=begin", "=end"}},
+		quotes:        []string{`"`, `'`},
+		keywords:      words("alias and begin break case class def defined? do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require"),
+	}
+	shellSyntax = &syntax{
+		lineComments: []string{"#"},
+		quotes:       []string{`"`, `'`},
+		raw:          words("'"),
+		keywords:     words("if then else elif fi case esac for while until do done in function return local export select break continue"),
+	}
+	hashSyntax = &syntax{
+		lineComments: []string{"#"},
+		quotes:       []string{`"`, `'`},
+	}
+	sqlSyntax = &syntax{
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`'`, `"`},
+		caseless:      true,
+		keywords:      words("select from where insert into values update set delete create table drop alter index view join inner left right outer on as and or not null is in like between group by order having limit offset union all distinct primary key foreign references default case when then else end begin commit rollback"),
+	}
+	luaSyntax = &syntax{
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"--[[", "]]"}},
+		quotes:        []string{`"`, `'`},
+		keywords:      words("and break do else elseif end false for function goto if in local nil not or repeat return then true until while"),
+	}
+	haskellSyntax = &syntax{
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"{-", "-}"}},
+		quotes:        []string{`"`},
+		keywords:      words("case class data default deriving do else foreign if import in infix infixl infixr instance let module newtype of then type where"),
+	}
+	markupSyntax = &syntax{
+		blockComments: [][2]string{{"<!--", "-->"}},
+		quotes:        []string{`"`, `'`},
+	}
+	cssSyntax = &syntax{
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`},
+	}
+	plainSyntax = &syntax{}
+
+	// syntaxes by style class of model.Language
+	syntaxes = map[string]*syntax{
+		"c":          cSyntax,
+		"objc":       cSyntax,
+		"cuda":       cSyntax,
+		"glsl":       cSyntax,
+		"hlsl":       cSyntax,
+		"cpp":        cppSyntax,
+		"go":         goSyntax,
+		"java":       javaSyntax,
+		"kotlin":     javaSyntax,
+		"scala":      javaSyntax,
+		"groovy":     javaSyntax,
+		"csharp":     csSyntax,
+		"js":         jsSyntax,
+		"ts":         jsSyntax,
+		"dart":       jsSyntax,
+		"php":        jsSyntax,
+		"rust":       rustSyntax,
+		"swift":      swiftSyntax,
+		"py":         pySyntax,
+		"rb":         rbSyntax,
+		"shell":      shellSyntax,
+		"fish":       shellSyntax,
+		"perl":       hashSyntax,
+		"r":          hashSyntax,
+		"julia":      hashSyntax,
+		"yaml":       hashSyntax,
+		"toml":       hashSyntax,
+		"makefile":   hashSyntax,
+		"dockerfile": hashSyntax,
+		"compose":    hashSyntax,
+		"ini":        hashSyntax,
+		"dotenv":     hashSyntax,
+		"ignore":     hashSyntax,
+		"cmake":      hashSyntax,
+		"nix":        hashSyntax,
+		"hcl":        cSyntax,
+		"terraform":  cSyntax,
+		"proto":      cSyntax,
+		"graphql":    hashSyntax,
+		"powershell": hashSyntax,
+		"elixir":     hashSyntax,
+		"sql":        sqlSyntax,
+		"lua":        luaSyntax,
+		"haskell":    haskellSyntax,
+		"elm":        haskellSyntax,
+		"html":       markupSyntax,
+		"xml":        markupSyntax,
+		"svg":        markupSyntax,
+		"vue":        markupSyntax,
+		"svelte":     markupSyntax,
+		"md":         markupSyntax,
+		"css":        cssSyntax,
+		"scss":       cssSyntax,
+		"less":       cssSyntax,
+		"sass":       cssSyntax,
+		"json":       cssSyntax,
+	}
+)
+
+// getSyntax returns syntax of language style class,
+// plain text syntax if not known.
+func getSyntax(lang string) *syntax {
+	if s, ok := syntaxes[lang]; ok {
+		return s
+	}
+	return plainSyntax
+}
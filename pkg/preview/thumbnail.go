@@ -0,0 +1,104 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package preview
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+
+	// decoders of supported image formats
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+const (
+	// maxSamples is number of samples per axis averaged to one
+	// thumbnail pixel, which bounds the work for large images.
+	maxSamples = 4
+)
+
+// thumbnail returns img downscaled to fit size×size, encoded as PNG.
+// Images smaller than size are not scaled up.
+func thumbnail(img image.Image, size int) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+		if tw < 1 {
+			tw = 1
+		}
+		if th < 1 {
+			th = 1
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := b.Min.Y + y*h/th
+		y1 := b.Min.Y + (y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0 := b.Min.X + x*w/tw
+			x1 := b.Min.X + (x+1)*w/tw
+			dst.SetNRGBA(x, y, average(img, x0, y0, x1, y1))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// average returns average color of at most maxSamples×maxSamples
+// pixels spread over the rectangle.
+func average(img image.Image, x0, y0, x1, y1 int) color.NRGBA {
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	sx, sy := samples(x1-x0), samples(y1-y0)
+	var r, g, b, a, n uint64
+	for j := 0; j < sy; j++ {
+		y := y0 + (y1-y0)*j/sy
+		for i := 0; i < sx; i++ {
+			x := x0 + (x1-x0)*i/sx
+			// premultiplied 16-bit components
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			r += uint64(cr)
+			g += uint64(cg)
+			b += uint64(cb)
+			a += uint64(ca)
+			n++
+		}
+	}
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	// un-premultiply to 8 bits
+	return color.NRGBA{
+		R: uint8(r * 0xff / a),
+		G: uint8(g * 0xff / a),
+		B: uint8(b * 0xff / a),
+		A: uint8(a / n >> 8),
+	}
+}
+
+func samples(n int) int {
+	if n < maxSamples {
+		return n
+	}
+	return maxSamples
+}
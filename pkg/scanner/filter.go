@@ -0,0 +1,114 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/filemaps/filemaps/pkg/ignore"
+)
+
+// Sources of exclusions made by Filter
+const (
+	IncludeSource = "include"
+	SizeSource    = "size"
+	MtimeSource   = "mtime"
+	BinarySource  = "binary"
+
+	// sniffLength is number of bytes read when detecting binary files,
+	// same as git uses
+	sniffLength = 8000
+)
+
+// Filter selects files by name, size, modification time and content.
+// Zero values do not filter. Directories are not filtered.
+type Filter struct {
+	// Include contains gitignore style patterns relative to base,
+	// only files matching one of them are found
+	Include []string `json:"include"`
+	// MinSize and MaxSize are file size limits in bytes
+	MinSize int64 `json:"minSize"`
+	MaxSize int64 `json:"maxSize"`
+	// ModifiedAfter and ModifiedBefore limit modification times
+	ModifiedAfter  *time.Time `json:"modifiedAfter"`
+	ModifiedBefore *time.Time `json:"modifiedBefore"`
+	// SkipBinary excludes files containing null bytes
+	SkipBinary bool `json:"skipBinary"`
+}
+
+// fileFilter is Filter with compiled include patterns.
+type fileFilter struct {
+	Filter
+	include *ignore.Matcher
+}
+
+// IsZero tells if filter lets all files through.
+func (f Filter) IsZero() bool {
+	return len(f.Include) == 0 && f.MinSize == 0 && f.MaxSize == 0 &&
+		f.ModifiedAfter == nil && f.ModifiedBefore == nil && !f.SkipBinary
+}
+
+// Exclusion returns exclusion of file which does not pass the filter,
+// or nil if file passes. relative is slash separated path relative to
+// base.
+func (f Filter) Exclusion(file string, relative string, info os.FileInfo) *Exclusion {
+	return newFileFilter(f).exclusion(file, relative, info)
+}
+
+func newFileFilter(f Filter) *fileFilter {
+	ff := &fileFilter{Filter: f}
+	if len(f.Include) > 0 {
+		ff.include = &ignore.Matcher{}
+		ff.include.AddSource(IncludeSource, "", f.Include)
+	}
+	return ff
+}
+
+// exclusion checks cheap conditions first, content is read last.
+func (f *fileFilter) exclusion(file string, relative string, info os.FileInfo) *Exclusion {
+	if f.include != nil && !f.include.Excluded(relative, false) {
+		return &Exclusion{Path: relative, Source: IncludeSource}
+	}
+	size := info.Size()
+	if f.MinSize > 0 && size < f.MinSize {
+		return &Exclusion{Path: relative, Source: SizeSource, Pattern: "< " + strconv.FormatInt(f.MinSize, 10)}
+	}
+	if f.MaxSize > 0 && size > f.MaxSize {
+		return &Exclusion{Path: relative, Source: SizeSource, Pattern: "> " + strconv.FormatInt(f.MaxSize, 10)}
+	}
+	mtime := info.ModTime()
+	if f.ModifiedAfter != nil && mtime.Before(*f.ModifiedAfter) {
+		return &Exclusion{Path: relative, Source: MtimeSource, Pattern: "before " + f.ModifiedAfter.Format(time.RFC3339)}
+	}
+	if f.ModifiedBefore != nil && mtime.After(*f.ModifiedBefore) {
+		return &Exclusion{Path: relative, Source: MtimeSource, Pattern: "after " + f.ModifiedBefore.Format(time.RFC3339)}
+	}
+	if f.SkipBinary && isBinary(file) {
+		return &Exclusion{Path: relative, Source: BinarySource}
+	}
+	return nil
+}
+
+// isBinary tells if beginning of file contains a null byte.
+// Unreadable files are not considered binary.
+func isBinary(file string) bool {
+	fh, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer fh.Close()
+
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(fh, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
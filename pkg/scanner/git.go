@@ -0,0 +1,128 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Scan sources
+const (
+	// SourceFS lists files by walking directories
+	SourceFS = "fs"
+	// SourceGit lists files known to git
+	SourceGit = "git"
+)
+
+// walkGit emits files listed by git under dir. Map exclude patterns
+// and .filemapsignore are applied, ignore files of git are applied by
+// git itself. Returns false if dir is not in a git repository.
+func (w *Walker) walkGit(dir string) bool {
+	files, err := gitFiles(w.ctx, dir, w.opts.Untracked)
+	if err != nil {
+		if w.ctx.Err() != nil {
+			w.source = SourceGit
+			return true
+		}
+		log.WithFields(log.Fields{
+			"path": dir,
+			"err":  err,
+		}).Info("Could not list files with git, walking directories")
+		return false
+	}
+	w.source = SourceGit
+
+	relDir := w.relative(dir)
+	for _, name := range files {
+		if w.ctx.Err() != nil {
+			return true
+		}
+		if w.opts.MaxDepth > 0 && strings.Count(name, "/") >= w.opts.MaxDepth {
+			continue
+		}
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		relative := path.Join(relDir, name)
+		w.currentDir.Store(filepath.Dir(filePath))
+
+		file, err := os.Lstat(filePath)
+		if err != nil {
+			// tracked but deleted from work tree
+			continue
+		}
+		target := ""
+		if file.Mode()&os.ModeSymlink != 0 {
+			target = linkTarget(filePath)
+			if w.opts.FollowSymlinks {
+				if fi, err := os.Stat(filePath); err == nil {
+					file = fi
+				}
+			}
+		}
+		if file.IsDir() {
+			// submodule or symlinked directory
+			continue
+		}
+
+		atomic.AddInt64(&w.visited, 1)
+		e := rootExclusion(filePath, relative, target)
+		if e == nil {
+			e = w.exclusion(relative, file, w.after)
+		}
+		if e == nil {
+			e = w.filterFile(filePath, relative, file)
+		}
+		if e != nil {
+			if !w.emit(Item{Excluded: e}) {
+				return true
+			}
+			continue
+		}
+		if !w.emitFile(filePath, target) {
+			return true
+		}
+	}
+	return true
+}
+
+// gitFiles runs git ls-files in dir and returns slash separated paths
+// relative to dir. Untracked files not ignored by git are included if
+// untracked is true.
+func gitFiles(ctx context.Context, dir string, untracked bool) ([]string, error) {
+	args := []string{"ls-files", "-z", "--cached"}
+	if untracked {
+		args = append(args, "--others", "--exclude-standard")
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// unmerged files are listed once for each stage
+	seen := make(map[string]bool)
+	var files []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, name)
+	}
+	return files, nil
+}
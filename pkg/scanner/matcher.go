@@ -0,0 +1,176 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/filemaps/filemaps/pkg/ignore"
+)
+
+const (
+	// DepthSource is source of exclusions of files deeper than
+	// Options.MaxDepth.
+	DepthSource = "max-depth"
+	// UntrackedSource is source of exclusions of files not listed
+	// by git, with SourceGit.
+	UntrackedSource = "untracked"
+	// MissingSource is source of exclusions of files which do not
+	// exist.
+	MissingSource = "missing"
+)
+
+// Matcher tells if single files would be found by a scan of base with
+// the same options, so files created after a scan can be checked
+// without scanning again. It applies the same layered ignore files,
+// exclude patterns, depth limit, filter and git listing as Walk.
+// Ignore files and git are read once, so a Matcher should be used
+// only for a batch of files.
+type Matcher struct {
+	w *Walker
+	// gitignores are matchers of directories by relative path
+	gitignores map[string]*ignore.Matcher
+	// git contains files listed by git, nil if not using git
+	git map[string]bool
+}
+
+// NewMatcher returns Matcher for files under base.
+func NewMatcher(base string, opts Options) *Matcher {
+	w := &Walker{
+		base:   base,
+		opts:   opts,
+		ctx:    context.Background(),
+		before: &ignore.Matcher{},
+		after:  &ignore.Matcher{},
+	}
+	if opts.IgnoreFiles {
+		w.addFile(w.before, filepath.Join(base, gitInfoExcludeFile), gitInfoExcludeFile, "")
+		w.addFile(w.after, filepath.Join(base, filemapsIgnoreFile), filemapsIgnoreFile, "")
+	}
+	w.after.AddSource(ExcludeSource, "", opts.Exclude)
+	if !opts.Filter.IsZero() {
+		w.filter = newFileFilter(opts.Filter)
+	}
+	m := &Matcher{
+		w:          w,
+		gitignores: make(map[string]*ignore.Matcher),
+	}
+	if opts.Source == SourceGit {
+		files, err := gitFiles(w.ctx, base, opts.Untracked)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"path": base,
+				"err":  err,
+			}).Info("Could not list files with git, matching ignore files")
+		} else {
+			m.git = make(map[string]bool, len(files))
+			for _, name := range files {
+				m.git[name] = true
+			}
+		}
+	}
+	return m
+}
+
+// IsIgnoreFile tells if file at slash separated path relative to base
+// is an ignore file read by scans with Options.IgnoreFiles.
+func IsIgnoreFile(relative string) bool {
+	return path.Base(relative) == gitIgnoreFile ||
+		relative == gitInfoExcludeFile ||
+		relative == filemapsIgnoreFile
+}
+
+// Exclusion returns exclusion of file at path relative to base,
+// or nil if a scan would find the file.
+func (m *Matcher) Exclusion(relative string) *Exclusion {
+	relative = filepath.ToSlash(relative)
+	filePath := filepath.Join(m.w.base, filepath.FromSlash(relative))
+	parts := strings.Split(relative, "/")
+	if m.w.opts.MaxDepth > 0 && len(parts) > m.w.opts.MaxDepth {
+		return &Exclusion{Path: relative, Source: DepthSource}
+	}
+
+	file, err := os.Lstat(filePath)
+	if err != nil {
+		return &Exclusion{Path: relative, Source: MissingSource}
+	}
+	target := ""
+	if file.Mode()&os.ModeSymlink != 0 {
+		target = linkTarget(filePath)
+		if m.w.opts.FollowSymlinks {
+			if fi, err := os.Stat(filePath); err == nil {
+				file = fi
+			}
+		}
+	}
+	if e := rootExclusion(filePath, relative, target); e != nil {
+		return e
+	}
+
+	if m.git != nil {
+		if !m.git[relative] {
+			return &Exclusion{Path: relative, Source: UntrackedSource}
+		}
+		if e := m.w.exclusion(relative, file, m.w.after); e != nil {
+			return e
+		}
+	} else if e := m.ignored(parts, file); e != nil {
+		return e
+	}
+	return m.w.filterFile(filePath, relative, file)
+}
+
+// ignored returns exclusion of the file or its parent directories
+// by ignore files and exclude patterns, like readDir.
+func (m *Matcher) ignored(parts []string, file os.FileInfo) *Exclusion {
+	var gitignores *ignore.Matcher
+	for i, name := range parts {
+		relDir := strings.Join(parts[:i], "/")
+		if m.w.opts.IgnoreFiles {
+			gitignores = m.dirGitIgnores(gitignores, relDir)
+		}
+		exclude := ignore.Merge(m.w.before, gitignores, m.w.after)
+		relative := path.Join(relDir, name)
+		info := file
+		if i < len(parts)-1 {
+			info = dirInfo(name)
+		}
+		if e := m.w.exclusion(relative, info, exclude); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// dirGitIgnores returns parent extended with .gitignore file of
+// directory, reading each directory once.
+func (m *Matcher) dirGitIgnores(parent *ignore.Matcher, relDir string) *ignore.Matcher {
+	if gi, ok := m.gitignores[relDir]; ok {
+		return gi
+	}
+	dir := filepath.Join(m.w.base, filepath.FromSlash(relDir))
+	gi := m.w.addGitIgnore(parent, dir, relDir)
+	m.gitignores[relDir] = gi
+	return gi
+}
+
+// dirInfo is os.FileInfo of a parent directory, only name and
+// type are used for matching.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() os.FileMode  { return os.ModeDir }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() interface{}   { return nil }
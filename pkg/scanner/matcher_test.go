@@ -0,0 +1,58 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	base := writeTestFiles(t, map[string]string{
+		".git/info/exclude": "*.log\n",
+		".gitignore":        "*.tmp\nbuild/\n",
+		".filemapsignore":   "docs/\n",
+		"sub/.gitignore":    "!keep.tmp\nlocal.txt\n",
+		"a.go":              "",
+		"a.tmp":             "",
+		"a.log":             "",
+		"build/out.bin":     "",
+		"docs/index.md":     "",
+		"sub/keep.tmp":      "",
+		"sub/local.txt":     "",
+		"sub/b.go":          "",
+		"sub/debug.log":     "",
+		"sub/deep/c.go":     "",
+	})
+	defer os.RemoveAll(base)
+
+	m := NewMatcher(base, Options{
+		Exclude:     []string{"!a.log"},
+		IgnoreFiles: true,
+		MaxDepth:    2,
+	})
+	for path, source := range map[string]string{
+		"a.go":          "",
+		"a.log":         "",
+		"sub/b.go":      "",
+		"sub/keep.tmp":  "",
+		"a.tmp":         ".gitignore",
+		"build/out.bin": ".gitignore",
+		"docs/index.md": ".filemapsignore",
+		"sub/local.txt": "sub/.gitignore",
+		"sub/debug.log": ".git/info/exclude",
+		"sub/deep/c.go": DepthSource,
+		"missing.go":    MissingSource,
+	} {
+		e := m.Exclusion(path)
+		if source == "" && e != nil {
+			t.Errorf("Expected %s to match, excluded by %s", path, e.Source)
+		}
+		if source != "" && (e == nil || e.Source != source) {
+			t.Errorf("Expected %s to be excluded by %s, got %+v", path, source, e)
+		}
+	}
+}
@@ -6,119 +6,594 @@
 package scanner
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	log "github.com/Sirupsen/logrus"
+
+	"github.com/filemaps/filemaps/pkg/config"
+	"github.com/filemaps/filemaps/pkg/ignore"
 )
 
-func Scan(path string, base string, exclude []string) []string {
-	log.WithFields(log.Fields{
-		"path":    path,
-		"base":    base,
-		"exclude": exclude,
-	}).Info("Start")
+const (
+	// ExcludeSource is source of map exclude patterns in exclusions.
+	ExcludeSource = "exclude"
+	// GitSource is source of the implicit .git directory exclusion.
+	GitSource = "git"
+	// LoopSource is source of exclusions of symlinked directories
+	// pointing to their own parent directories.
+	LoopSource = "symlink-loop"
+	// RootsSource is source of exclusions of symlinks pointing
+	// outside allowed roots.
+	RootsSource = "allowed-roots"
+	// DefaultWorkers is number of directories read concurrently
+	// when Options.Workers is not set.
+	DefaultWorkers = 8
+
+	gitDir             = ".git"
+	gitIgnoreFile      = ".gitignore"
+	gitInfoExcludeFile = ".git/info/exclude"
+	filemapsIgnoreFile = ".filemapsignore"
+)
+
+// Options for Scan.
+type Options struct {
+	// Exclude contains gitignore patterns of the map.
+	// They override patterns from ignore files.
+	Exclude []string
+	// IgnoreFiles enables reading nested .gitignore files,
+	// .git/info/exclude and .filemapsignore
+	IgnoreFiles bool
+	// Workers is number of directories read concurrently
+	Workers int
+	// MaxFiles stops scan when given number of files is found,
+	// 0 means no limit
+	MaxFiles int
+	// MaxDepth is number of directory levels scanned, 1 scans only
+	// files directly in the scanned dir, 0 means no limit
+	MaxDepth int
+	// FollowSymlinks enables scanning symlinked directories.
+	// Symlinks are listed as files otherwise.
+	FollowSymlinks bool
+	// Filter selects found files by name, size, mtime and content
+	Filter Filter
+	// Source is SourceGit to list files tracked by git. Directories
+	// are walked if dir is not in a git repository.
+	Source string
+	// Untracked adds untracked files not ignored by git, with SourceGit
+	Untracked bool
+}
+
+// Exclusion tells which pattern excluded a file or directory.
+// Contents of excluded directories are not listed.
+type Exclusion struct {
+	Path    string `json:"path"`
+	Source  string `json:"source"`
+	Line    int    `json:"line"`
+	Pattern string `json:"pattern"`
+}
+
+// Result of Scan.
+type Result struct {
+	Files    []string    `json:"files"`
+	Excluded []Exclusion `json:"excluded"`
+	// Targets contains real paths of symlinked files
+	Targets map[string]string `json:"targets"`
+	// Truncated is true if scan was stopped by MaxFiles
+	Truncated bool `json:"truncated"`
+	// Source tells if files were listed by git or by walking directories
+	Source string `json:"source"`
+}
+
+// Item is a file found or excluded by Walk.
+type Item struct {
+	// File is path of a found file
+	File string
+	// Target is real path of File if File is a symlink
+	Target string
+	// Excluded is set instead of File for excluded files
+	// and directories
+	Excluded *Exclusion
+}
+
+// Walker streams items of a running scan.
+type Walker struct {
+	// Items receives found files and exclusions in no particular order.
+	// It is closed when scan is finished, cancelled or MaxFiles is
+	// reached.
+	Items <-chan Item
+
+	items  chan Item
+	base   string
+	opts   Options
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	// before contains patterns overridden by .gitignore files
+	before *ignore.Matcher
+	// after contains patterns overriding .gitignore files
+	after *ignore.Matcher
+	// filter is nil if opts.Filter is zero
+	filter *fileFilter
+	// source is SourceGit if files were listed by git
+	source string
+	// err is set if scan could not be started
+	err error
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []dirJob
+	pending int // queued and running jobs
+
+	files     int64
+	visited   int64
+	truncated int32
+	// currentDir is the directory read most recently
+	currentDir atomic.Value
+}
+
+// dirJob is a directory waiting to be read.
+type dirJob struct {
+	dir        string
+	relDir     string
+	depth      int
+	gitignores *ignore.Matcher
+	// ancestors are IDs of the directory and its parents,
+	// tracked when following symlinks
+	ancestors []fileID
+}
+
+// fileID identifies a file by device and inode.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// Scan finds files under dir and returns them sorted so files of the
+// same directory are together. Exclude patterns are relative to base.
+// If ctx is cancelled, partial result and ctx.Err() are returned.
+func Scan(ctx context.Context, dir string, base string, opts Options) (*Result, error) {
+	w := Walk(ctx, dir, base, opts)
+	result := &Result{
+		Files:    make([]string, 0),
+		Excluded: make([]Exclusion, 0),
+		Targets:  make(map[string]string),
+	}
+	for item := range w.Items {
+		if item.Excluded != nil {
+			result.Excluded = append(result.Excluded, *item.Excluded)
+			continue
+		}
+		result.Files = append(result.Files, item.File)
+		if item.Target != "" {
+			result.Targets[item.File] = item.Target
+		}
+	}
+	SortPaths(result.Files)
+	sort.Slice(result.Excluded, func(i, j int) bool {
+		return result.Excluded[i].Path < result.Excluded[j].Path
+	})
+	result.Truncated = w.Truncated()
+	result.Source = w.Source()
 
-	files := readDir(path, base, exclude)
 	log.WithFields(log.Fields{
-		"files": files,
+		"files":     len(result.Files),
+		"excluded":  len(result.Excluded),
+		"truncated": result.Truncated,
+		"source":    result.Source,
 	}).Info("Files found by scanning")
-	return files
+	return result, w.Err()
 }
 
-func readDir(path string, base string, exclude []string) []string {
-	var found []string
+// Walk starts scanning dir with a pool of workers and returns Walker
+// streaming the results. Exclude patterns are relative to base and
+// layered from lowest to highest precedence: .git/info/exclude,
+// .gitignore files (deeper override shallower), .filemapsignore and
+// the map exclude patterns.
+func Walk(ctx context.Context, dir string, base string, opts Options) *Walker {
+	log.WithFields(log.Fields{
+		"path":        dir,
+		"base":        base,
+		"exclude":     opts.Exclude,
+		"ignoreFiles": opts.IgnoreFiles,
+		"maxFiles":    opts.MaxFiles,
+		"maxDepth":    opts.MaxDepth,
+		"symlinks":    opts.FollowSymlinks,
+		"filtered":    !opts.Filter.IsZero(),
+		"source":      opts.Source,
+	}).Info("Start")
 
-	files, err := ioutil.ReadDir(path)
-	if err != nil {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	w := &Walker{
+		items:  make(chan Item, opts.Workers),
+		base:   base,
+		opts:   opts,
+		parent: ctx,
+		before: &ignore.Matcher{},
+		after:  &ignore.Matcher{},
+	}
+	w.Items = w.items
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.cond = sync.NewCond(&w.mu)
 
+	job := dirJob{
+		dir:    dir,
+		relDir: w.relative(dir),
+		depth:  1,
+	}
+	if opts.IgnoreFiles {
+		w.addFile(w.before, filepath.Join(base, gitInfoExcludeFile), gitInfoExcludeFile, "")
+		w.addFile(w.after, filepath.Join(base, filemapsIgnoreFile), filemapsIgnoreFile, "")
+		job.gitignores = w.parentGitIgnores(job.relDir)
+	}
+	w.after.AddSource(ExcludeSource, "", opts.Exclude)
+	if !opts.Filter.IsZero() {
+		w.filter = newFileFilter(opts.Filter)
+	}
+	if opts.FollowSymlinks {
+		if fi, err := os.Stat(dir); err == nil {
+			if id, ok := getFileID(dir, fi); ok {
+				job.ancestors = []fileID{id}
+			}
+		}
+	}
+
+	go func() {
+		if err := config.CheckPath(dir); err != nil {
+			w.err = err
+		} else if opts.Source != SourceGit || !w.walkGit(dir) {
+			w.walkDirs(job)
+		}
+		w.cancel()
+		close(w.items)
+	}()
+	return w
+}
+
+// walkDirs reads directories with a pool of workers, starting from job,
+// and returns when all are read or the scan is stopped.
+func (w *Walker) walkDirs(job dirJob) {
+	w.source = SourceFS
+	w.push(job)
+
+	// wake up idle workers when scan is cancelled
+	go func() {
+		<-w.ctx.Done()
+		w.mu.Lock()
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+}
+
+// Source tells how files were listed, SourceGit or SourceFS.
+// It must be called after Items is closed.
+func (w *Walker) Source() string {
+	return w.source
+}
+
+// Truncated tells if scan was stopped by MaxFiles.
+func (w *Walker) Truncated() bool {
+	return atomic.LoadInt32(&w.truncated) == 1
+}
+
+// Progress returns number of files visited so far, including excluded
+// files, and the directory read most recently.
+func (w *Walker) Progress() (int, string) {
+	dir, _ := w.currentDir.Load().(string)
+	return int(atomic.LoadInt64(&w.visited)), dir
+}
+
+// Err returns error if scan was cancelled or dir is outside allowed
+// roots. It must be called after Items is closed.
+func (w *Walker) Err() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.parent.Err()
+}
+
+// work reads directories from queue until the scan is done.
+func (w *Walker) work() {
+	for {
+		job, ok := w.next()
+		if !ok {
+			return
+		}
+		for _, sub := range w.readDir(job) {
+			w.push(sub)
+		}
+		w.mu.Lock()
+		w.pending--
+		if w.pending == 0 {
+			w.cond.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// next waits for a queued job. Returns false when all jobs are done
+// or the scan is stopped.
+func (w *Walker) next() (dirJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 && w.ctx.Err() == nil {
+		w.cond.Wait()
+	}
+	if len(w.queue) == 0 || w.ctx.Err() != nil {
+		return dirJob{}, false
+	}
+	job := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return job, true
+}
+
+func (w *Walker) push(job dirJob) {
+	w.mu.Lock()
+	w.queue = append(w.queue, job)
+	w.pending++
+	w.cond.Signal()
+	w.mu.Unlock()
+}
+
+// readDir emits files of directory and returns jobs for subdirectories.
+func (w *Walker) readDir(job dirJob) []dirJob {
+	w.currentDir.Store(job.dir)
+	gitignores := job.gitignores
+	if w.opts.IgnoreFiles {
+		gitignores = w.addGitIgnore(gitignores, job.dir, job.relDir)
+	}
+	exclude := ignore.Merge(w.before, gitignores, w.after)
+
+	files, err := ioutil.ReadDir(job.dir)
+	if err != nil {
 		log.WithFields(log.Fields{
-			"path": path,
+			"path": job.dir,
 			"err":  err,
 		}).Error("Error when reading dir")
 	}
 
-	var dirs []os.FileInfo
+	var subs []dirJob
 	for _, file := range files {
-		filePath := filepath.Join(path, file.Name())
+		filePath := filepath.Join(job.dir, file.Name())
+		relative := path.Join(job.relDir, file.Name())
+
+		target := ""
+		if file.Mode()&os.ModeSymlink != 0 {
+			target = linkTarget(filePath)
+			if w.opts.FollowSymlinks {
+				if fi, err := os.Stat(filePath); err == nil {
+					file = fi
+				}
+			}
+		}
 
-		if isExcluded(filePath, file.IsDir(), base, exclude) {
+		if !file.IsDir() {
+			atomic.AddInt64(&w.visited, 1)
+		}
+		e := rootExclusion(filePath, relative, target)
+		if e == nil {
+			e = w.exclusion(relative, file, exclude)
+		}
+		if e != nil {
+			if !w.emit(Item{Excluded: e}) {
+				return nil
+			}
 			continue
 		}
 		if file.IsDir() {
-			dirs = append(dirs, file)
-		} else {
-			log.Info(filePath)
-			found = append(found, filePath)
+			if w.opts.MaxDepth > 0 && job.depth >= w.opts.MaxDepth {
+				continue
+			}
+			sub := dirJob{
+				dir:        filePath,
+				relDir:     relative,
+				depth:      job.depth + 1,
+				gitignores: gitignores,
+			}
+			if w.opts.FollowSymlinks {
+				if id, ok := getFileID(filePath, file); ok {
+					if job.hasAncestor(id) {
+						log.WithFields(log.Fields{
+							"path":   filePath,
+							"target": target,
+						}).Warn("Symlink loop")
+						if !w.emit(Item{Excluded: &Exclusion{Path: relative, Source: LoopSource}}) {
+							return nil
+						}
+						continue
+					}
+					// full slice expression makes append copy, as
+					// ancestors are shared with sibling directories
+					sub.ancestors = append(job.ancestors[:len(job.ancestors):len(job.ancestors)], id)
+				}
+			}
+			subs = append(subs, sub)
+		} else if e := w.filterFile(filePath, relative, file); e != nil {
+			if !w.emit(Item{Excluded: e}) {
+				return nil
+			}
+		} else if !w.emitFile(filePath, target) {
+			return nil
 		}
 	}
+	return subs
+}
 
-	for _, dir := range dirs {
-		subFound := readDir(filepath.Join(path, dir.Name()), base, exclude)
-		found = append(found, subFound...)
+// hasAncestor tells if directory with given ID is the directory of
+// the job or one of its parents.
+func (job *dirJob) hasAncestor(id fileID) bool {
+	for _, a := range job.ancestors {
+		if a == id {
+			return true
+		}
 	}
-	return found
+	return false
 }
 
-func isExcluded(path string, isDir bool, base string, exclude []string) bool {
-	relative, err := filepath.Rel(base, path)
+// linkTarget returns real path of symlink, or link content if the link
+// cannot be resolved.
+func linkTarget(link string) string {
+	if target, err := filepath.EvalSymlinks(link); err == nil {
+		if abs, err := filepath.Abs(target); err == nil {
+			return abs
+		}
+		return target
+	}
+	target, err := os.Readlink(link)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"base": base,
-			"path": path,
-		}).Error("Could not get relative path")
+		return ""
+	}
+	return target
+}
+
+// emitFile sends found file unless MaxFiles is reached.
+func (w *Walker) emitFile(filePath string, target string) bool {
+	if w.opts.MaxFiles > 0 && atomic.AddInt64(&w.files, 1) > int64(w.opts.MaxFiles) {
+		atomic.StoreInt32(&w.truncated, 1)
+		w.cancel()
 		return false
 	}
-	for i := len(exclude) - 1; i >= 0; i-- {
-		pattern := strings.Trim(exclude[i], " ")
-		if len(pattern) == 0 {
-			// empty line
-			continue
-		}
+	log.WithFields(log.Fields{
+		"path":   filePath,
+		"target": target,
+	}).Debug("Found file")
+	return w.emit(Item{File: filePath, Target: target})
+}
 
-		if pattern[0] == '#' {
-			// ignore comments
-			continue
-		}
+// emit sends item to Items. Returns false if scan is stopped.
+func (w *Walker) emit(item Item) bool {
+	select {
+	case w.items <- item:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
 
-		// patterns starting with '!' are inverted
-		invert := false
-		if pattern[0] == '!' {
-			invert = true
-			pattern = pattern[1:]
+// exclusion returns exclusion of file or nil if file is not excluded.
+func (w *Walker) exclusion(relative string, file os.FileInfo, exclude *ignore.Matcher) *Exclusion {
+	if w.opts.IgnoreFiles && file.IsDir() && file.Name() == gitDir {
+		return &Exclusion{
+			Path:    relative,
+			Source:  GitSource,
+			Pattern: gitDir + "/",
 		}
+	}
+	p := exclude.ExcludedBy(relative, file.IsDir())
+	if p == nil {
+		return nil
+	}
+	return &Exclusion{
+		Path:    relative,
+		Source:  p.Source,
+		Line:    p.Line,
+		Pattern: p.Text,
+	}
+}
 
-		// patterns starting with '/'
-		if pattern[0] == '/' {
-			pattern = pattern[1:]
-		}
+// rootExclusion returns exclusion of symlink pointing outside
+// allowed roots, or nil.
+func rootExclusion(filePath string, relative string, target string) *Exclusion {
+	if target == "" || config.CheckPath(filePath) == nil {
+		return nil
+	}
+	return &Exclusion{Path: relative, Source: RootsSource}
+}
 
-		// patterns ending with '/' must match to directory
-		mustBeDir := false
-		if pattern[len(pattern)-1] == '/' {
-			mustBeDir = true
-			pattern = pattern[0 : len(pattern)-1]
-		}
+// filterFile returns exclusion of file not passing the filter.
+func (w *Walker) filterFile(filePath string, relative string, file os.FileInfo) *Exclusion {
+	if w.filter == nil {
+		return nil
+	}
+	return w.filter.exclusion(filePath, relative, file)
+}
 
-		name := relative
-		// if patterns does not contain "/", compare with base name
-		if !strings.ContainsAny(pattern, "/") {
-			name = filepath.Base(relative)
-		}
+// parentGitIgnores reads .gitignore files from base down to the parent
+// of relDir, for scans starting below base.
+func (w *Walker) parentGitIgnores(relDir string) *ignore.Matcher {
+	if relDir == "" || relDir == ".." || strings.HasPrefix(relDir, "../") || filepath.IsAbs(relDir) {
+		return nil
+	}
+	m := w.addGitIgnore(nil, w.base, "")
+	parts := strings.Split(relDir, "/")
+	for i := 1; i < len(parts); i++ {
+		rel := strings.Join(parts[:i], "/")
+		m = w.addGitIgnore(m, filepath.Join(w.base, filepath.FromSlash(rel)), rel)
+	}
+	return m
+}
 
-		matched, err := filepath.Match(pattern, name)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"pattern": pattern,
-				"name":    name,
-			}).Error("Invalid exclude pattern")
-			continue
-		}
-		if matched && (!mustBeDir || isDir) {
-			return !invert
-		}
+// addGitIgnore returns gitignores extended with .gitignore file of dir.
+// gitignores is not modified as it is shared with sibling directories.
+func (w *Walker) addGitIgnore(gitignores *ignore.Matcher, dir string, relDir string) *ignore.Matcher {
+	m := &ignore.Matcher{}
+	w.addFile(m, filepath.Join(dir, gitIgnoreFile), path.Join(relDir, gitIgnoreFile), relDir)
+	if m.Len() == 0 {
+		return gitignores
 	}
-	return false
+	return ignore.Merge(gitignores, m)
+}
+
+// addFile adds patterns from ignore file, if it exists, to matcher.
+func (w *Walker) addFile(m *ignore.Matcher, file string, source string, base string) {
+	err := m.AddFile(file, source, base)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"path": file,
+			"err":  err,
+		}).Error("Could not read ignore file")
+	}
+}
+
+// relative returns slash separated path relative to base,
+// empty for base itself.
+func (w *Walker) relative(p string) string {
+	relative, err := filepath.Rel(w.base, p)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"base": w.base,
+			"path": p,
+		}).Error("Could not get relative path")
+		return filepath.ToSlash(p)
+	}
+	if relative == "." {
+		return ""
+	}
+	return filepath.ToSlash(relative)
+}
+
+// SortPaths sorts paths by directory and then by file name,
+// so files of the same directory are next to each other.
+func SortPaths(paths []string) {
+	sort.Slice(paths, func(i, j int) bool {
+		return LessPath(paths[i], paths[j])
+	})
+}
+
+// LessPath compares paths by directory and then by file name.
+func LessPath(a string, b string) bool {
+	dirA, dirB := filepath.Dir(a), filepath.Dir(b)
+	if dirA != dirB {
+		return dirA < dirB
+	}
+	return filepath.Base(a) < filepath.Base(b)
 }
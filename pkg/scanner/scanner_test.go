@@ -0,0 +1,311 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanIgnoreFiles(t *testing.T) {
+	base := writeTestFiles(t, map[string]string{
+		".git/info/exclude": "*.log\n",
+		".gitignore":        "*.tmp\nbuild/\n",
+		".filemapsignore":   "docs/\n",
+		"sub/.gitignore":    "!keep.tmp\nlocal.txt\n",
+		"a.go":              "",
+		"a.tmp":             "",
+		"a.log":             "",
+		"build/out.bin":     "",
+		"docs/index.md":     "",
+		"sub/keep.tmp":      "",
+		"sub/local.txt":     "",
+		"sub/b.go":          "",
+		"sub/debug.log":     "",
+	})
+	defer os.RemoveAll(base)
+
+	result, err := Scan(context.Background(), base, base, Options{
+		Exclude:     []string{"!a.log"},
+		IgnoreFiles: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found []string
+	for _, f := range result.Files {
+		rel, _ := filepath.Rel(base, f)
+		found = append(found, filepath.ToSlash(rel))
+	}
+	sort.Strings(found)
+	expected := []string{
+		".filemapsignore", ".gitignore", "a.go", "a.log",
+		"sub/.gitignore", "sub/b.go", "sub/keep.tmp",
+	}
+	if len(found) != len(expected) {
+		t.Fatal("Expected", expected, "got", found)
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			t.Error("Expected", expected[i], "got", found[i])
+		}
+	}
+
+	sources := make(map[string]string)
+	for _, e := range result.Excluded {
+		sources[e.Path] = e.Source
+	}
+	for path, source := range map[string]string{
+		".git":          GitSource,
+		"a.tmp":         ".gitignore",
+		"build":         ".gitignore",
+		"docs":          ".filemapsignore",
+		"sub/local.txt": "sub/.gitignore",
+		"sub/debug.log": ".git/info/exclude",
+	} {
+		if sources[path] != source {
+			t.Errorf("Expected %s to be excluded by %s, got %q", path, source, sources[path])
+		}
+	}
+}
+
+func TestScanLimits(t *testing.T) {
+	base := writeTestFiles(t, map[string]string{
+		"1.txt":       "",
+		"a/2.txt":     "",
+		"a/b/3.txt":   "",
+		"a/b/c/4.txt": "",
+	})
+	defer os.RemoveAll(base)
+
+	result, err := Scan(context.Background(), base, base, Options{MaxDepth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 2 || result.Truncated {
+		t.Error("Expected 2 files with MaxDepth 2, got", result.Files)
+	}
+
+	result, err = Scan(context.Background(), base, base, Options{MaxFiles: 3, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 3 || !result.Truncated {
+		t.Error("Expected 3 files and truncated result with MaxFiles 3, got", result.Files)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = Scan(ctx, base, base, Options{}); err != context.Canceled {
+		t.Error("Expected cancelled scan to return context.Canceled, got", err)
+	}
+}
+
+// writeTestFiles creates files to a new temporary dir and returns
+// path of the dir.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	base, err := ioutil.TempDir("", "filemaps-scanner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(base, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return base
+}
+
+func TestScanFollowSymlinks(t *testing.T) {
+	base := writeTestFiles(t, map[string]string{
+		"a/1.txt":   "",
+		"lib/2.txt": "",
+	})
+	defer os.RemoveAll(base)
+
+	links := map[string]string{
+		"a/loop":   "..",
+		"a/lib":    "../lib",
+		"a/2.link": "../lib/2.txt",
+	}
+	for link, target := range links {
+		if err := os.Symlink(filepath.FromSlash(target), filepath.Join(base, filepath.FromSlash(link))); err != nil {
+			t.Skip("Symlinks not supported:", err)
+		}
+	}
+
+	result, err := Scan(context.Background(), filepath.Join(base, "a"), base, Options{FollowSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// loop leads back to base, which contains a again
+	found := make(map[string]bool)
+	for _, f := range result.Files {
+		rel, _ := filepath.Rel(base, f)
+		found[filepath.ToSlash(rel)] = true
+	}
+	for _, path := range []string{"a/1.txt", "a/lib/2.txt", "a/2.link", "a/loop/lib/2.txt"} {
+		if !found[path] {
+			t.Error("Expected to find", path, "got", result.Files)
+		}
+	}
+	if len(result.Excluded) != 1 || result.Excluded[0].Source != LoopSource {
+		t.Error("Expected one symlink loop, got", result.Excluded)
+	}
+	target := result.Targets[filepath.Join(base, "a", "2.link")]
+	if filepath.Base(target) != "2.txt" {
+		t.Error("Expected symlink target to be recorded, got", target)
+	}
+}
+
+func TestScanFilter(t *testing.T) {
+	base := writeTestFiles(t, map[string]string{
+		"services/api/main.go":    "package main\n",
+		"services/api/api.proto":  "syntax = \"proto3\";\n",
+		"services/api/logo.go":    "\x00\x01binary",
+		"services/api/big.go":     "package main\n// padding padding padding\n",
+		"services/api/README.md":  "# API\n",
+		"services/api/empty.go":   "",
+		"tools/gen.go":            "package main\n",
+		"services/web/old.go":     "package web\n",
+		"services/web/index.html": "<html></html>\n",
+	})
+	defer os.RemoveAll(base)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(filepath.Join(base, "services", "web", "old.go"), old, old)
+
+	after := time.Now().Add(-24 * time.Hour)
+	result, err := Scan(context.Background(), base, base, Options{
+		Filter: Filter{
+			Include:       []string{"services/**/*.go", "services/**/*.proto"},
+			MinSize:       1,
+			MaxSize:       30,
+			ModifiedAfter: &after,
+			SkipBinary:    true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found []string
+	for _, f := range result.Files {
+		rel, _ := filepath.Rel(base, f)
+		found = append(found, filepath.ToSlash(rel))
+	}
+	sort.Strings(found)
+	expected := []string{"services/api/api.proto", "services/api/main.go"}
+	if len(found) != len(expected) {
+		t.Fatal("Expected", expected, "got", found)
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			t.Error("Expected", expected[i], "got", found[i])
+		}
+	}
+
+	sources := map[string]string{
+		"services/api/logo.go":    BinarySource,
+		"services/api/big.go":     SizeSource,
+		"services/api/empty.go":   SizeSource,
+		"services/api/README.md":  IncludeSource,
+		"tools/gen.go":            IncludeSource,
+		"services/web/old.go":     MtimeSource,
+		"services/web/index.html": IncludeSource,
+	}
+	for _, e := range result.Excluded {
+		if sources[e.Path] != e.Source {
+			t.Errorf("%s excluded by %s, expected %s", e.Path, e.Source, sources[e.Path])
+		}
+		delete(sources, e.Path)
+	}
+	if len(sources) > 0 {
+		t.Error("Not excluded:", sources)
+	}
+}
+
+func TestScanGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	base := writeTestFiles(t, map[string]string{
+		".gitignore":      "*.log\n",
+		"a.go":            "",
+		"sub/b.go":        "",
+		"sub/excluded.go": "",
+		"untracked.txt":   "",
+		"debug.log":       "",
+	})
+	defer os.RemoveAll(base)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", ".gitignore", "a.go", "sub"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = base
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatal(err, string(out))
+		}
+	}
+	os.Remove(filepath.Join(base, "a.go"))
+
+	scan := func(dir string, untracked bool) ([]string, string) {
+		result, err := Scan(context.Background(), dir, base, Options{
+			Exclude:   []string{"sub/excluded.go"},
+			Source:    SourceGit,
+			Untracked: untracked,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []string
+		for _, f := range result.Files {
+			rel, _ := filepath.Rel(base, f)
+			found = append(found, filepath.ToSlash(rel))
+		}
+		sort.Strings(found)
+		return found, result.Source
+	}
+
+	tests := []struct {
+		untracked bool
+		expected  []string
+	}{
+		{false, []string{".gitignore", "sub/b.go"}},
+		{true, []string{".gitignore", "sub/b.go", "untracked.txt"}},
+	}
+	for _, test := range tests {
+		found, source := scan(base, test.untracked)
+		if source != SourceGit {
+			t.Errorf("Expected source %s, got %s", SourceGit, source)
+		}
+		if strings.Join(found, ",") != strings.Join(test.expected, ",") {
+			t.Error("Expected", test.expected, "got", found)
+		}
+	}
+
+	// directory outside a repository is walked
+	os.RemoveAll(filepath.Join(base, ".git"))
+	found, source := scan(base, false)
+	if source != SourceFS {
+		t.Errorf("Expected source %s, got %s", SourceFS, source)
+	}
+	if len(found) != 4 {
+		t.Error("Expected 4 files, got", found)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// IndexVersion is version of index files, indexes of other
+	// versions are rebuilt.
+	IndexVersion = 1
+)
+
+// Index is a persistent trigram index of files. Files not changed
+// since they were indexed are skipped by searches when they do not
+// contain all trigrams required by the query. Files are indexed when
+// they are searched, so the index speeds up repeated searches.
+type Index struct {
+	path string
+
+	mu      sync.Mutex
+	data    indexData
+	changed bool
+}
+
+type indexData struct {
+	Version int                     `json:"version"`
+	Base    string                  `json:"base"`
+	Files   map[string]*indexedFile `json:"files"`
+}
+
+type indexedFile struct {
+	Size int64 `json:"size"`
+	// Modified is modification time in nanoseconds
+	Modified int64 `json:"modified"`
+	Binary   bool  `json:"binary"`
+	// Trigrams are sorted trigrams of the file in lower case
+	Trigrams []uint32 `json:"trigrams"`
+}
+
+// OpenIndex reads index of files under base from path. Index is empty
+// if the file does not exist or was written for another base.
+func OpenIndex(path string, base string) (*Index, error) {
+	idx := &Index{
+		path: path,
+		data: indexData{
+			Version: IndexVersion,
+			Base:    base,
+			Files:   make(map[string]*indexedFile),
+		},
+	}
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var data indexData
+	if err := json.Unmarshal(bs, &data); err != nil {
+		// broken index is rebuilt
+		return idx, nil
+	}
+	if data.Version == IndexVersion && data.Base == base && data.Files != nil {
+		idx.data = data
+	}
+	return idx, nil
+}
+
+// Base returns base directory of indexed files.
+func (idx *Index) Base() string {
+	return idx.data.Base
+}
+
+// Len returns number of indexed files.
+func (idx *Index) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.data.Files)
+}
+
+// Prune removes files not in paths from the index.
+func (idx *Index) Prune(paths []string) {
+	keep := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		keep[path] = true
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for path := range idx.data.Files {
+		if !keep[path] {
+			delete(idx.data.Files, path)
+			idx.changed = true
+		}
+	}
+}
+
+// Write writes index to its file if it has changed.
+func (idx *Index) Write() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.changed {
+		return nil
+	}
+	data, err := json.Marshal(idx.data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0700); err != nil {
+		return err
+	}
+	// write to temporary file first so index is never left partial
+	tmp := idx.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, idx.path); err != nil {
+		return err
+	}
+	idx.changed = false
+	return nil
+}
+
+// mayMatch tells if file may contain required trigrams. Known is false
+// if file is not indexed or has changed since.
+func (idx *Index) mayMatch(path string, info os.FileInfo, required []uint32) (ok bool, known bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	f := idx.data.Files[path]
+	if f == nil || f.Size != info.Size() || f.Modified != info.ModTime().UnixNano() {
+		return false, false
+	}
+	if f.Binary {
+		return false, true
+	}
+	for _, t := range required {
+		i := sort.Search(len(f.Trigrams), func(i int) bool {
+			return f.Trigrams[i] >= t
+		})
+		if i == len(f.Trigrams) || f.Trigrams[i] != t {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// add indexes file contents.
+func (idx *Index) add(path string, info os.FileInfo, data []byte, binary bool) {
+	f := &indexedFile{
+		Size:     info.Size(),
+		Modified: info.ModTime().UnixNano(),
+		Binary:   binary,
+	}
+	if !binary {
+		f.Trigrams = trigrams(data)
+	}
+	idx.mu.Lock()
+	idx.data.Files[path] = f
+	idx.changed = true
+	idx.mu.Unlock()
+}
+
+// trigrams returns sorted trigrams of data within lines,
+// in ASCII lower case.
+func trigrams(data []byte) []uint32 {
+	set := make(map[uint32]bool)
+	var t uint32
+	n := 0
+	for _, c := range data {
+		if c == '\n' {
+			n = 0
+			continue
+		}
+		t = (t<<8 | uint32(lower(c))) & 0xffffff
+		if n++; n >= 3 {
+			set[t] = true
+		}
+	}
+	list := make([]uint32, 0, len(set))
+	for t := range set {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i] < list[j]
+	})
+	return list
+}
+
+// requiredTrigrams returns trigrams every line matching re contains.
+// Only literal text concatenated at top level of the expression is
+// considered, which is enough for the common queries.
+func requiredTrigrams(re *regexp.Regexp) []uint32 {
+	sre, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	var (
+		runs [][]byte
+		cur  []byte
+		buf  [utf8.UTFMax]byte
+	)
+	flush := func() {
+		if len(cur) >= 3 {
+			runs = append(runs, cur)
+		}
+		cur = nil
+	}
+	var walk func(r *syntax.Regexp)
+	walk = func(r *syntax.Regexp) {
+		switch r.Op {
+		case syntax.OpConcat:
+			for _, sub := range r.Sub {
+				walk(sub)
+			}
+		case syntax.OpCapture:
+			walk(r.Sub[0])
+		case syntax.OpLiteral:
+			fold := r.Flags&syntax.FoldCase != 0
+			for _, c := range r.Rune {
+				if fold && !foldsToASCII(c) {
+					flush()
+					continue
+				}
+				n := utf8.EncodeRune(buf[:], c)
+				cur = append(cur, buf[:n]...)
+			}
+		default:
+			flush()
+		}
+	}
+	walk(sre)
+	flush()
+
+	set := make(map[uint32]bool)
+	for _, run := range runs {
+		for _, t := range trigrams(run) {
+			set[t] = true
+		}
+	}
+	list := make([]uint32, 0, len(set))
+	for t := range set {
+		list = append(list, t)
+	}
+	return list
+}
+
+// foldsToASCII tells if lower casing ASCII bytes finds all case
+// variants of c. For example "k" also matches the Kelvin sign.
+func foldsToASCII(c rune) bool {
+	for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+		if c > unicode.MaxASCII || f > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// Package search implements full-text search of files.
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultLimit is number of matching lines returned by default.
+	DefaultLimit = 100
+	// MaxFileMatches is number of matching lines returned per file.
+	MaxFileMatches = 100
+
+	// maxWorkers is maximum number of files searched concurrently.
+	maxWorkers = 8
+	// maxFileBytes is size of largest file searched.
+	maxFileBytes = 8 * 1024 * 1024
+	// maxSnippetLength is number of bytes of a matching line returned.
+	maxSnippetLength = 200
+	// sniffLength is number of bytes checked for NUL to skip binaries.
+	sniffLength = 8000
+)
+
+// ErrEmptyQuery is returned for empty queries.
+var ErrEmptyQuery = errors.New("empty query")
+
+// Options for Search.
+type Options struct {
+	Query string
+	// Regex queries are regular expressions, others are literal text
+	Regex bool
+	// CaseSensitive queries match case exactly
+	CaseSensitive bool
+	// Limit is number of matching lines returned, 0 for DefaultLimit
+	Limit int
+	// Index is trigram index used to skip files, optional
+	Index *Index
+}
+
+// LineMatch is a line of a file matching the query.
+type LineMatch struct {
+	// Line is 1-based line number
+	Line int `json:"line"`
+	// Text is the line, shortened around the first match if long
+	Text string `json:"text"`
+	// Ranges are byte offsets of matches in Text
+	Ranges [][2]int `json:"ranges"`
+}
+
+// FileMatch contains matching lines of a file.
+type FileMatch struct {
+	// Path is relative to base
+	Path  string      `json:"path"`
+	Lines []LineMatch `json:"lines"`
+	// Truncated tells if file has more matching lines than in Lines
+	Truncated bool `json:"truncated"`
+}
+
+// Result of Search.
+type Result struct {
+	Files []FileMatch `json:"files"`
+	// Total is number of all matching lines
+	Total int `json:"total"`
+	// Searched is number of files read, others were skipped
+	// by the index
+	Searched int `json:"searched"`
+	// Truncated tells if there are more matches than returned
+	Truncated bool `json:"truncated"`
+}
+
+// Compile returns regular expression of query options.
+func Compile(opts Options) (*regexp.Regexp, error) {
+	if opts.Query == "" {
+		return nil, ErrEmptyQuery
+	}
+	expr := opts.Query
+	if !opts.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if !opts.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// Search searches files, given relative to base, on a bounded pool of
+// workers. Files which cannot be read, binary files and very large
+// files are skipped. Files are returned in order of path, lines of
+// them in order of line number.
+func Search(ctx context.Context, base string, paths []string, opts Options) (*Result, error) {
+	re, err := Compile(opts)
+	if err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	var required []uint32
+	if opts.Index != nil {
+		required = requiredTrigrams(re)
+	}
+
+	jobs := make(chan string)
+	var (
+		mu       sync.Mutex
+		files    []FileMatch
+		total    int
+		searched int
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < workers(len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				fm, n, read := searchFile(base, path, re, opts.Index, required)
+				mu.Lock()
+				if read {
+					searched++
+				}
+				if n > 0 {
+					files = append(files, fm)
+					total += n
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, path := range paths {
+		select {
+		case jobs <- path:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	result := &Result{
+		Files:    make([]FileMatch, 0),
+		Total:    total,
+		Searched: searched,
+	}
+	for _, fm := range files {
+		if limit <= 0 {
+			result.Truncated = true
+			break
+		}
+		if len(fm.Lines) > limit {
+			fm.Lines = fm.Lines[:limit]
+			fm.Truncated = true
+			result.Truncated = true
+		}
+		limit -= len(fm.Lines)
+		result.Files = append(result.Files, fm)
+	}
+	return result, nil
+}
+
+func workers(files int) int {
+	n := runtime.NumCPU()
+	if n > maxWorkers {
+		n = maxWorkers
+	}
+	if n > files {
+		n = files
+	}
+	return n
+}
+
+// searchFile returns matching lines of file and number of them.
+// Read tells if file was read, it is not if the index shows the file
+// cannot match.
+func searchFile(base string, path string, re *regexp.Regexp, idx *Index, required []uint32) (fm FileMatch, n int, read bool) {
+	fm.Path = path
+	full := filepath.Join(base, path)
+	info, err := os.Stat(full)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileBytes {
+		return
+	}
+	if idx != nil {
+		if ok, known := idx.mayMatch(path, info, required); known && !ok {
+			return
+		}
+	}
+
+	data, err := ioutil.ReadFile(full)
+	if err != nil {
+		return
+	}
+	read = true
+	binary := isBinary(data)
+	if idx != nil {
+		idx.add(path, info, data, binary)
+	}
+	if binary {
+		return
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 64*1024), maxFileBytes)
+	for line := 1; s.Scan(); line++ {
+		text := bytes.TrimRight(s.Bytes(), "\r")
+		locs := re.FindAllIndex(text, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		n++
+		if len(fm.Lines) == MaxFileMatches {
+			fm.Truncated = true
+			continue
+		}
+		fm.Lines = append(fm.Lines, snippet(line, text, locs))
+	}
+	return
+}
+
+func isBinary(data []byte) bool {
+	if len(data) > sniffLength {
+		data = data[:sniffLength]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// snippet returns matching line, shortening long lines around the
+// first match.
+func snippet(line int, text []byte, locs [][]int) LineMatch {
+	start, end := 0, len(text)
+	if end > maxSnippetLength {
+		start = locs[0][0] - maxSnippetLength/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxSnippetLength
+		if end > len(text) {
+			end = len(text)
+		}
+		// do not split UTF-8 sequences
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+
+	m := LineMatch{
+		Line:   line,
+		Text:   string(text[start:end]),
+		Ranges: make([][2]int, 0, len(locs)),
+	}
+	for _, loc := range locs {
+		if loc[0] >= end || loc[1] <= start {
+			continue
+		}
+		r := [2]int{loc[0] - start, loc[1] - start}
+		if r[0] < 0 {
+			r[0] = 0
+		}
+		if r[1] > end-start {
+			r[1] = end - start
+		}
+		m.Ranges = append(m.Ranges, r)
+	}
+	return m
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func getTestDir(t *testing.T) string {
+	base, err := ioutil.TempDir("", "filemaps-search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"main.go":    "package main\n\n// SessionToken is secret\nvar SessionToken string\n",
+		"README.md":  "Sessions\r\nsessiontoken in lower case\r\n",
+		"empty.txt":  "",
+		"binary.bin": "SessionToken\x00",
+		"long.txt":   strings.Repeat("x", 1000) + "SessionToken" + strings.Repeat("y", 1000) + "\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(base, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return base
+}
+
+var testPaths = []string{"README.md", "binary.bin", "empty.txt", "long.txt", "main.go", "missing.txt"}
+
+func TestSearch(t *testing.T) {
+	base := getTestDir(t)
+	defer os.RemoveAll(base)
+
+	var tests = []struct {
+		opts  Options
+		files []string
+		total int
+	}{
+		{Options{Query: "SessionToken", CaseSensitive: true}, []string{"long.txt", "main.go"}, 3},
+		{Options{Query: "sessiontoken"}, []string{"README.md", "long.txt", "main.go"}, 4},
+		{Options{Query: `Session\w*\b`, Regex: true, CaseSensitive: true}, []string{"README.md", "long.txt", "main.go"}, 4},
+		{Options{Query: "session.", Regex: true}, []string{"README.md", "long.txt", "main.go"}, 5},
+		{Options{Query: "session."}, []string{}, 0},
+		{Options{Query: "token", Limit: 2}, []string{"README.md", "long.txt"}, 4},
+	}
+	for _, test := range tests {
+		result, err := Search(context.Background(), base, testPaths, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var files []string
+		for _, fm := range result.Files {
+			files = append(files, fm.Path)
+		}
+		if strings.Join(files, " ") != strings.Join(test.files, " ") || result.Total != test.total {
+			t.Errorf("Search %+v returned %v with %d lines, expected %v with %d",
+				test.opts, files, result.Total, test.files, test.total)
+		}
+	}
+
+	result, _ := Search(context.Background(), base, testPaths, Options{Query: "token"})
+	m := result.Files[2].Lines[1]
+	if m.Line != 4 || m.Text != "var SessionToken string" || len(m.Ranges) != 1 || m.Ranges[0] != [2]int{11, 16} {
+		t.Errorf("Unexpected line match %+v", m)
+	}
+	m = result.Files[1].Lines[0]
+	if len(m.Text) != maxSnippetLength || m.Text[m.Ranges[0][0]:m.Ranges[0][1]] != "Token" {
+		t.Errorf("Unexpected snippet %+v", m)
+	}
+
+	if _, err := Search(context.Background(), base, testPaths, Options{}); err != ErrEmptyQuery {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+	if _, err := Search(context.Background(), base, testPaths, Options{Query: "(", Regex: true}); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	base := getTestDir(t)
+	defer os.RemoveAll(base)
+	path := filepath.Join(base, "index", "test.index")
+
+	idx, err := OpenIndex(path, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Query: "SessionToken", Index: idx}
+	result, err := Search(context.Background(), base, testPaths, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Searched != 5 || idx.Len() != 5 {
+		t.Errorf("Expected 5 files searched and indexed, got %d and %d", result.Searched, idx.Len())
+	}
+	idx.Prune(testPaths[1:])
+	if err := idx.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	// repeated search reads only files which may match
+	if idx, err = OpenIndex(path, base); err != nil {
+		t.Fatal(err)
+	}
+	if idx.Len() != 4 {
+		t.Errorf("Expected 4 files in read index, got %d", idx.Len())
+	}
+	opts.Index = idx
+	result, _ = Search(context.Background(), base, testPaths, opts)
+	if result.Searched != 3 || len(result.Files) != 3 {
+		t.Errorf("Expected 3 files searched and matching, got %d and %d", result.Searched, len(result.Files))
+	}
+
+	// changed files are searched again
+	ioutil.WriteFile(filepath.Join(base, "empty.txt"), []byte("sessionTOKEN"), 0600)
+	result, _ = Search(context.Background(), base, testPaths, opts)
+	if len(result.Files) != 4 {
+		t.Errorf("Expected 4 matching files after change, got %d", len(result.Files))
+	}
+
+	// index of other base is not used
+	if idx, _ = OpenIndex(path, filepath.Join(base, "other")); idx.Len() != 0 {
+		t.Errorf("Expected empty index for other base, got %d files", idx.Len())
+	}
+}
+
+func TestRequiredTrigrams(t *testing.T) {
+	var tests = []struct {
+		expr     string
+		trigrams int
+	}{
+		{"abcd", 2},
+		{"(?i)abcd", 2},
+		{"ab.cd", 0},
+		{"abc|def", 0},
+		{"x(abc)y", 3},
+		{"abc+def", 1},
+		// "k" and "s" fold to non-ASCII runes
+		{"(?i)risky", 0},
+		{"risky", 3},
+	}
+	for _, test := range tests {
+		if n := len(requiredTrigrams(regexp.MustCompile(test.expr))); n != test.trigrams {
+			t.Errorf("Expected %d trigrams for %q, got %d", test.trigrams, test.expr, n)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// +build linux
+
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// inotifyMask is events watched in each directory.
+	inotifyMask = syscall.IN_CREATE | syscall.IN_MOVED_TO |
+		syscall.IN_DELETE | syscall.IN_MOVED_FROM
+	// inotifyBufferSize is size of buffer for reading events.
+	inotifyBufferSize = 64 * 1024
+)
+
+// inotify watches a directory tree with one inotify watch
+// per directory.
+type inotify struct {
+	root   string
+	skip   SkipFunc
+	fd     int
+	closed int32
+	events chan Event
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu  sync.Mutex
+	wds map[int32]string // watch descriptor -> relative directory
+}
+
+func newNativeWatcher(root string, skip SkipFunc) (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	w := &inotify{
+		root:   root,
+		skip:   skip,
+		fd:     fd,
+		events: make(chan Event),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		wds:    make(map[int32]string),
+	}
+	// running out of watches falls back to polling
+	if err := w.addTree("", false); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *inotify) Events() <-chan Event {
+	return w.events
+}
+
+// Close removes all watches, which wakes up the blocking read.
+func (w *inotify) Close() error {
+	atomic.StoreInt32(&w.closed, 1)
+	close(w.stop)
+	w.mu.Lock()
+	for wd := range w.wds {
+		syscall.InotifyRmWatch(w.fd, uint32(wd))
+	}
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+func (w *inotify) run() {
+	defer close(w.done)
+	defer close(w.events)
+	defer syscall.Close(w.fd)
+
+	buf := make([]byte, inotifyBufferSize)
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if atomic.LoadInt32(&w.closed) == 1 {
+			return
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"path": w.root,
+				"err":  err,
+			}).Error("Could not read inotify events")
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			end := start + int(raw.Len)
+			name := strings.TrimRight(string(buf[start:end]), "\x00")
+			offset = end
+			if !w.handle(raw.Wd, raw.Mask, name) {
+				return
+			}
+		}
+
+		w.mu.Lock()
+		empty := len(w.wds) == 0
+		w.mu.Unlock()
+		if empty {
+			// root directory is gone
+			return
+		}
+	}
+}
+
+// handle converts inotify event to Event. Returns false if
+// watcher is closed.
+func (w *inotify) handle(wd int32, mask uint32, name string) bool {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		log.WithFields(log.Fields{
+			"path": w.root,
+		}).Warn("Inotify event queue overflow, events lost")
+		return true
+	}
+
+	w.mu.Lock()
+	dir, ok := w.wds[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.wds, wd)
+	}
+	w.mu.Unlock()
+	if !ok || name == "" {
+		return true
+	}
+
+	rel := filepath.Join(dir, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+	if w.skip != nil && w.skip(filepath.ToSlash(rel), isDir) {
+		return true
+	}
+
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		if isDir {
+			// files may be created before the watch is added,
+			// so existing files are reported too
+			if err := w.addTree(rel, true); err != nil {
+				log.WithFields(log.Fields{
+					"path": rel,
+					"err":  err,
+				}).Error("Could not watch directory")
+			}
+			return atomic.LoadInt32(&w.closed) == 0
+		}
+		return w.send(Event{Path: rel, Op: Create})
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		if isDir {
+			w.removeTree(rel)
+		}
+		return w.send(Event{Path: rel, Op: Remove})
+	}
+	return true
+}
+
+// addTree adds watches to directory rel and its subdirectories.
+// Files found are reported if emit is true.
+func (w *inotify) addTree(rel string, emit bool) error {
+	return filepath.Walk(filepath.Join(w.root, rel), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// removed while walking
+			return nil
+		}
+		r, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return nil
+		}
+		if r == "." {
+			r = ""
+		} else if w.skip != nil && w.skip(filepath.ToSlash(r), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+			if err != nil {
+				return err
+			}
+			w.mu.Lock()
+			w.wds[int32(wd)] = r
+			w.mu.Unlock()
+		} else if emit && !w.send(Event{Path: r, Op: Create}) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// removeTree removes watches of directory rel and its subdirectories.
+// Needed for directories moved away, which keep their watches.
+func (w *inotify) removeTree(rel string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	prefix := rel + string(filepath.Separator)
+	for wd, dir := range w.wds {
+		if dir == rel || strings.HasPrefix(dir, prefix) {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.wds, wd)
+		}
+	}
+}
+
+// send sends event unless watcher is closed.
+func (w *inotify) send(e Event) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package watcher
+
+import (
+	"errors"
+)
+
+func newNativeWatcher(root string, skip SkipFunc) (Watcher, error) {
+	return nil, errors.New("native watching not supported")
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+// Package watcher reports files created and removed under a directory.
+// It uses inotify on Linux and polling elsewhere.
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Op is type of file system change.
+type Op int
+
+// Op enum
+const (
+	Create Op = iota
+	Remove
+)
+
+// Converts Op to string
+func (o Op) String() string {
+	switch o {
+	case Create:
+		return "create"
+	case Remove:
+		return "remove"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a file system change.
+type Event struct {
+	// Path is relative to the watched directory. Remove events of
+	// directories are not repeated for their contents.
+	Path string
+	Op   Op
+}
+
+// SkipFunc tells if slash separated path relative to the watched
+// directory should not be watched.
+type SkipFunc func(path string, isDir bool) bool
+
+// Watcher reports changes under a directory.
+type Watcher interface {
+	// Events returns channel of changes, closed when watcher is closed
+	Events() <-chan Event
+	// Close stops watching
+	Close() error
+}
+
+// New starts watching files under root. Native watcher is used when
+// available, polling with given interval otherwise.
+func New(root string, skip SkipFunc, interval time.Duration) (Watcher, error) {
+	w, err := newNativeWatcher(root, skip)
+	if err == nil {
+		return w, nil
+	}
+	log.WithFields(log.Fields{
+		"path": root,
+		"err":  err,
+	}).Info("Native file watching not available, polling")
+	return newPoller(root, skip, interval)
+}
+
+// NewNative starts watching files under root with native watcher.
+// Error is returned if native watching is not available.
+func NewNative(root string, skip SkipFunc) (Watcher, error) {
+	return newNativeWatcher(root, skip)
+}
+
+// poller finds changes by listing files periodically.
+type poller struct {
+	root     string
+	skip     SkipFunc
+	interval time.Duration
+	files    map[string]bool
+	events   chan Event
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newPoller(root string, skip SkipFunc, interval time.Duration) (*poller, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+	p := &poller{
+		root:     root,
+		skip:     skip,
+		interval: interval,
+		events:   make(chan Event),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	p.files = p.list()
+	go p.run()
+	return p, nil
+}
+
+func (p *poller) Events() <-chan Event {
+	return p.events
+}
+
+func (p *poller) Close() error {
+	close(p.stop)
+	<-p.done
+	return nil
+}
+
+func (p *poller) run() {
+	defer close(p.done)
+	defer close(p.events)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+
+		files := p.list()
+		for path := range files {
+			if !p.files[path] && !p.send(Event{Path: path, Op: Create}) {
+				return
+			}
+		}
+		for path := range p.files {
+			if !files[path] && !p.send(Event{Path: path, Op: Remove}) {
+				return
+			}
+		}
+		p.files = files
+	}
+}
+
+// send sends event unless poller is closed.
+func (p *poller) send(e Event) bool {
+	select {
+	case p.events <- e:
+		return true
+	case <-p.stop:
+		return false
+	}
+}
+
+// list returns relative paths of files under root.
+func (p *poller) list() map[string]bool {
+	files := make(map[string]bool)
+	filepath.Walk(p.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// file removed while walking
+			return nil
+		}
+		rel, err := filepath.Rel(p.root, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		if p.skip != nil && p.skip(filepath.ToSlash(rel), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files[rel] = true
+		}
+		return nil
+	})
+	return files
+}
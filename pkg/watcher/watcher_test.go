@@ -0,0 +1,70 @@
+// Copyright (c) 2017, CodeBoy. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the
+// license that can be found in the LICENSE file.
+
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchers(t *testing.T) {
+	newWatchers := map[string]func(string, SkipFunc) (Watcher, error){
+		"native": newNativeWatcher,
+		"poller": func(root string, skip SkipFunc) (Watcher, error) {
+			return newPoller(root, skip, 20*time.Millisecond)
+		},
+	}
+	for name, newWatcher := range newWatchers {
+		root, err := ioutil.TempDir("", "filemaps-watch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Mkdir(filepath.Join(root, "skipped"), 0700)
+		ioutil.WriteFile(filepath.Join(root, "old.txt"), nil, 0600)
+
+		skip := func(path string, isDir bool) bool {
+			return path == "skipped"
+		}
+		w, err := newWatcher(root, skip)
+		if err != nil {
+			os.RemoveAll(root)
+			t.Logf("%s: not available: %v", name, err)
+			continue
+		}
+
+		ioutil.WriteFile(filepath.Join(root, "skipped", "a.txt"), nil, 0600)
+		os.MkdirAll(filepath.Join(root, "sub", "deep"), 0700)
+		ioutil.WriteFile(filepath.Join(root, "sub", "deep", "new.txt"), nil, 0600)
+		os.Remove(filepath.Join(root, "old.txt"))
+
+		want := map[Event]bool{
+			{Path: filepath.Join("sub", "deep", "new.txt"), Op: Create}: true,
+			{Path: "old.txt", Op: Remove}:                               true,
+		}
+		timeout := time.After(5 * time.Second)
+		for len(want) > 0 {
+			select {
+			case e := <-w.Events():
+				if filepath.Base(filepath.Dir(e.Path)) == "skipped" {
+					t.Errorf("%s: event of skipped directory %v", name, e)
+				}
+				delete(want, e)
+			case <-timeout:
+				t.Errorf("%s: events not received: %v", name, want)
+				want = nil
+			}
+		}
+
+		w.Close()
+		if _, ok := <-w.Events(); ok {
+			t.Errorf("%s: events not closed", name)
+		}
+		os.RemoveAll(root)
+	}
+}